@@ -10,6 +10,7 @@ import (
 "runtime"
 			_ "net/http/pprof"
 	"recon-service/internal/config"
+	"recon-service/internal/reconcile/dictionary"
 	serverhttp "recon-service/server/http"
 )
 
@@ -21,7 +22,18 @@ func main() {
 	cfg := config.Load()
 	logger := config.SetupLogger(cfg)
 
-	r := serverhttp.NewRouter(cfg, logger)
+	// словари нормализации компилируются один раз при старте
+	// историю версий обязательно храним на диске, только если включён admin API
+	dicts, err := dictionary.Load(cfg.DictDir, cfg.AdminToken != "")
+	if err != nil {
+		logger.Fatal().Err(err).Str("dir", cfg.DictDir).Msg("load dictionaries")
+	}
+	if err := dicts.MemoryOnly(); err != nil {
+		logger.Warn().Err(err).Str("dir", cfg.DictDir).Msg("dictionary history is kept in memory only")
+	}
+	logger.Info().Strs("dictionaries", dicts.Names()).Msg("dictionaries loaded")
+
+	r := serverhttp.NewRouter(cfg, logger, dicts)
 
 	srv := &http.Server{Addr: cfg.Addr(), Handler: r}
 	logger.Info().Str("addr", cfg.Addr()).Msg("server starting")
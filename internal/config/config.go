@@ -14,6 +14,8 @@ type Config struct {
 	LogLevel     string
 	MaxUploadMB  int
 	LogFile      string
+	DictDir      string // каталог со словарями нормализации (*.json)
+	AdminToken   string // токен для /admin/*; пусто — admin API выключен
 }
 
 func Load() Config {
@@ -27,6 +29,8 @@ func Load() Config {
 		LogLevel:     getenv("LOG_LEVEL", "debug"),
 		MaxUploadMB:  mb,
 		LogFile:      getenv("LOG_FILE", "logs/recon-service.log"),
+		DictDir:      getenv("DICT_DIR", "dictionaries"),
+		AdminToken:   getenv("ADMIN_TOKEN", ""),
 	}
 }
 
@@ -0,0 +1,246 @@
+// Package decimal — десятичные числа фиксированной точности для количеств:
+// значение = coef · 10^-scale. Сложение и вычитание точные, округление —
+// только явное (Round) с выбранным режимом, JSON — ровно те цифры, что
+// хранятся ("0.3", а не 0.30000000000000004).
+package decimal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal — неизменяемое значение; нулевое значение — 0. Операции не
+// меняют операнды и возвращают новое значение.
+type Decimal struct {
+	coef  *big.Int // nil — 0
+	scale int32    // число знаков после запятой (>= 0)
+}
+
+// MaxScale — предел знаков после запятой при разборе
+const MaxScale = 30
+
+var (
+	ErrSyntax = errors.New("decimal: invalid syntax")
+	ErrScale  = fmt.Errorf("decimal: more than %d fractional digits", MaxScale)
+)
+
+var ten = big.NewInt(10)
+
+// pow10 — 10^n
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(ten, big.NewInt(int64(n)), nil)
+}
+
+func (d Decimal) int() *big.Int {
+	if d.coef == nil {
+		return new(big.Int)
+	}
+	return d.coef
+}
+
+// New — coef · 10^-scale
+func New(coef int64, scale int32) Decimal {
+	if scale < 0 {
+		return Decimal{coef: new(big.Int).Mul(big.NewInt(coef), pow10(-scale))}
+	}
+	return Decimal{coef: big.NewInt(coef), scale: scale}
+}
+
+// Parse разбирает каноническую запись: "-12", "0.5", "1234.500", "1e-3".
+// Разделитель — точка, без пробелов (RU-форматы — fileio.ParseRuDecimal).
+func Parse(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Decimal{}, ErrSyntax
+	}
+	var exp int64
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, ErrSyntax
+		}
+		exp, s = e, s[:i]
+	}
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg, s = true, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	intPart, frac, _ := strings.Cut(s, ".")
+	if intPart == "" && frac == "" {
+		return Decimal{}, ErrSyntax
+	}
+	digits := intPart + frac
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return Decimal{}, ErrSyntax
+		}
+	}
+	coef, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Decimal{}, ErrSyntax
+	}
+	if neg {
+		coef.Neg(coef)
+	}
+	scale := int64(len(frac)) - exp
+	if scale < 0 {
+		coef.Mul(coef, pow10(int32(-scale)))
+		scale = 0
+	}
+	if scale > MaxScale {
+		return Decimal{}, ErrScale
+	}
+	return Decimal{coef: coef, scale: int32(scale)}, nil
+}
+
+// MustParse — Parse для констант; паникует на ошибке
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// FromFloat — кратчайшая десятичная запись float64 (0.1 → 0.1, а не
+// 0.1000000000000000055…). NaN и ±Inf дают 0.
+func FromFloat(f float64) Decimal {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}
+	}
+	d, err := Parse(strconv.FormatFloat(f, 'f', -1, 64))
+	if err != nil {
+		return Decimal{}
+	}
+	return d
+}
+
+// rescale приводит к большему числу знаков (без потерь)
+func (d Decimal) rescale(scale int32) *big.Int {
+	c := d.int()
+	if scale == d.scale {
+		return c
+	}
+	return new(big.Int).Mul(c, pow10(scale-d.scale))
+}
+
+func align(a, b Decimal) (x, y *big.Int, scale int32) {
+	scale = a.scale
+	if b.scale > scale {
+		scale = b.scale
+	}
+	return a.rescale(scale), b.rescale(scale), scale
+}
+
+// Add — a + b (точно)
+func (d Decimal) Add(o Decimal) Decimal {
+	x, y, scale := align(d, o)
+	return Decimal{coef: new(big.Int).Add(x, y), scale: scale}
+}
+
+// Sub — a - b (точно)
+func (d Decimal) Sub(o Decimal) Decimal {
+	x, y, scale := align(d, o)
+	return Decimal{coef: new(big.Int).Sub(x, y), scale: scale}
+}
+
+// Mul — a · b (точно; знаков после запятой — сумма знаков)
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{coef: new(big.Int).Mul(d.int(), o.int()), scale: d.scale + o.scale}
+}
+
+// Neg — -a
+func (d Decimal) Neg() Decimal {
+	return Decimal{coef: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Abs — |a|
+func (d Decimal) Abs() Decimal {
+	if d.Sign() >= 0 {
+		return d
+	}
+	return d.Neg()
+}
+
+// Sign — -1, 0 или +1
+func (d Decimal) Sign() int { return d.int().Sign() }
+
+// IsZero — равно ли нулю (при любом числе знаков)
+func (d Decimal) IsZero() bool { return d.Sign() == 0 }
+
+// Cmp — -1, 0, +1 (1.50 == 1.5)
+func (d Decimal) Cmp(o Decimal) int {
+	x, y, _ := align(d, o)
+	return x.Cmp(y)
+}
+
+// Scale — число знаков после запятой
+func (d Decimal) Scale() int32 { return d.scale }
+
+// Unscaled — значение в единицах 10^-scale (лишние знаки округляются
+// half_up); false — результат не помещается в int64
+func (d Decimal) Unscaled(scale int32) (int64, bool) {
+	if scale < 0 {
+		scale = 0
+	}
+	c := d.rescale(scale)
+	if scale < d.scale {
+		c = d.Round(scale, HalfUp).int()
+	}
+	if !c.IsInt64() {
+		return 0, false
+	}
+	return c.Int64(), true
+}
+
+// Float64 — ближайший float64 (для эвристик и весов, не для сумм)
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// String — точная запись с d.Scale() знаками после запятой: "-1.500"
+func (d Decimal) String() string {
+	c := d.int()
+	if d.scale == 0 {
+		return c.String()
+	}
+	neg := c.Sign() < 0
+	digits := new(big.Int).Abs(c).String()
+	if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	cut := len(digits) - int(d.scale)
+	s := digits[:cut] + "." + digits[cut:]
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
+
+// MarshalJSON — JSON-число с точными цифрами
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON принимает число или строку с числом
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "null" {
+		*d = Decimal{}
+		return nil
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
@@ -0,0 +1,139 @@
+package decimal
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestRound(t *testing.T) {
+	// значения: половина, меньше и больше половины шага, обоих знаков;
+	// 3.5 и -3.5 — для half_even (нечётная целая часть)
+	in := []string{"2.5", "2.4", "2.6", "3.5", "-2.5", "-2.4", "-2.6", "-3.5"}
+	for _, tc := range []struct {
+		mode RoundingMode
+		want []string
+	}{
+		{HalfUp, []string{"3", "2", "3", "4", "-3", "-2", "-3", "-4"}},
+		{HalfEven, []string{"2", "2", "3", "4", "-2", "-2", "-3", "-4"}},
+		{HalfDown, []string{"2", "2", "3", "3", "-2", "-2", "-3", "-3"}},
+		{Down, []string{"2", "2", "2", "3", "-2", "-2", "-2", "-3"}},
+		{Up, []string{"3", "3", "3", "4", "-3", "-3", "-3", "-4"}},
+		{Floor, []string{"2", "2", "2", "3", "-3", "-3", "-3", "-4"}},
+		{Ceiling, []string{"3", "3", "3", "4", "-2", "-2", "-2", "-3"}},
+	} {
+		for i, s := range in {
+			if got := MustParse(s).Round(0, tc.mode).String(); got != tc.want[i] {
+				t.Errorf("%s.Round(0, %s) = %s, ждём %s", s, tc.mode, got, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestRoundPlaces(t *testing.T) {
+	for _, tc := range []struct {
+		in     string
+		places int32
+		mode   RoundingMode
+		want   string
+	}{
+		{"1.005", 2, HalfUp, "1.01"},
+		{"1.005", 2, HalfEven, "1.00"},
+		{"-1.005", 2, HalfUp, "-1.01"},
+		{"0.0049", 2, HalfUp, "0.00"},
+		{"-0.05", 1, HalfEven, "0.0"}, // отрицательного нуля нет
+		{"1.5", 3, HalfUp, "1.5"},     // знаков меньше — как есть
+		{"1.20", 1, Up, "1.2"},        // остаток 0 — без сдвига
+		{"12.5", -1, HalfUp, "13"},    // places < 0 — как 0
+	} {
+		if got := MustParse(tc.in).Round(tc.places, tc.mode).String(); got != tc.want {
+			t.Errorf("%s.Round(%d, %s) = %s, ждём %s", tc.in, tc.places, tc.mode, got, tc.want)
+		}
+	}
+}
+
+func TestParseString(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"1.500", "1.500"}, // знаки после запятой сохраняются
+		{"-0.05", "-0.05"},
+		{"1e-3", "0.001"},
+		{"1.5E2", "150"},
+		{"-12", "-12"},
+		{"+7", "7"},
+		{".5", "0.5"},
+		{"5.", "5"},
+		{" 0.3 ", "0.3"},
+	} {
+		d, err := Parse(tc.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.in, err)
+			continue
+		}
+		if got := d.String(); got != tc.want {
+			t.Errorf("Parse(%q).String() = %s, ждём %s", tc.in, got, tc.want)
+		}
+		// String даёт каноническую запись, которую Parse читает обратно
+		if back, err := Parse(d.String()); err != nil || back.String() != tc.want || back.Cmp(d) != 0 {
+			t.Errorf("Parse(%q) обратно: %v, %v", d.String(), back, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{"", "-", ".", "1.2.3", "1,5", "abc", "1e", "1e+x", "--1"} {
+		if _, err := Parse(s); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Parse(%q): %v, ждём ErrSyntax", s, err)
+		}
+	}
+	if _, err := Parse("1e-31"); !errors.Is(err, ErrScale) {
+		t.Errorf("Parse(1e-31): %v, ждём ErrScale", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	for _, tc := range []struct {
+		d    Decimal
+		want string
+	}{
+		{Decimal{}, "0"},
+		{New(5, 3), "0.005"},
+		{New(-15, 1), "-1.5"},
+		{New(12, -2), "1200"},
+		{MustParse("1.500"), "1.500"},
+	} {
+		b, err := json.Marshal(tc.d)
+		if err != nil || string(b) != tc.want {
+			t.Errorf("Marshal(%v) = %s, %v; ждём %s", tc.d, b, err, tc.want)
+		}
+		var back Decimal
+		if err := json.Unmarshal(b, &back); err != nil || back.Cmp(tc.d) != 0 {
+			t.Errorf("Unmarshal(%s) = %v, %v", b, back, err)
+		}
+	}
+	var d Decimal
+	if err := json.Unmarshal([]byte(`"0.25"`), &d); err != nil || d.String() != "0.25" {
+		t.Errorf("Unmarshal строки: %v, %v", d, err)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a, b := MustParse("0.1"), MustParse("0.2")
+	if got := a.Add(b).String(); got != "0.3" {
+		t.Errorf("0.1+0.2 = %s", got)
+	}
+	if got := a.Sub(MustParse("1.25")).String(); got != "-1.15" {
+		t.Errorf("0.1-1.25 = %s", got)
+	}
+	if got := MustParse("1.5").Mul(MustParse("-0.2")).String(); got != "-0.30" {
+		t.Errorf("1.5·-0.2 = %s", got)
+	}
+	if MustParse("1.50").Cmp(MustParse("1.5")) != 0 {
+		t.Error("1.50 != 1.5")
+	}
+	if v, ok := MustParse("1.0004").Unscaled(4); !ok || v != 10004 {
+		t.Errorf("Unscaled(4) = %d, %v", v, ok)
+	}
+	if v, ok := MustParse("1.25").Unscaled(1); !ok || v != 13 {
+		t.Errorf("Unscaled(1) = %d, %v", v, ok)
+	}
+}
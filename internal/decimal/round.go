@@ -0,0 +1,81 @@
+package decimal
+
+import (
+	"math/big"
+	"strings"
+)
+
+// RoundingMode — режим округления Round
+type RoundingMode string
+
+const (
+	HalfUp   RoundingMode = "half_up"   // 0.5 → 1, -0.5 → -1 (бухгалтерское)
+	HalfEven RoundingMode = "half_even" // 0.5 → 0, 1.5 → 2 (банковское)
+	HalfDown RoundingMode = "half_down" // 0.5 → 0, 0.6 → 1
+	Down     RoundingMode = "down"      // к нулю (отбросить)
+	Up       RoundingMode = "up"        // от нуля
+	Floor    RoundingMode = "floor"     // к -∞
+	Ceiling  RoundingMode = "ceiling"   // к +∞
+)
+
+// Modes — поддерживаемые режимы (для валидации и подсказок)
+func Modes() []RoundingMode {
+	return []RoundingMode{HalfUp, HalfEven, HalfDown, Down, Up, Floor, Ceiling}
+}
+
+// ParseMode — режим по имени (регистр не важен); пусто — HalfUp
+func ParseMode(s string) (RoundingMode, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return HalfUp, true
+	}
+	for _, m := range Modes() {
+		if string(m) == s {
+			return m, true
+		}
+	}
+	return "", false
+}
+
+// Round округляет до places знаков после запятой. Если знаков и так не
+// больше, значение возвращается как есть (1.5 при places=3 остаётся 1.5).
+func (d Decimal) Round(places int32, mode RoundingMode) Decimal {
+	if places < 0 {
+		places = 0
+	}
+	if d.scale <= places {
+		return d
+	}
+	div := pow10(d.scale - places)
+	q, r := new(big.Int).QuoRem(d.int(), div, new(big.Int)) // усечение к нулю
+	if r.Sign() == 0 {
+		return Decimal{coef: q, scale: places}
+	}
+
+	sign := d.Sign()
+	// сравнение остатка с половиной шага: 2|r| vs div
+	half := new(big.Int).Abs(r)
+	half.Lsh(half, 1)
+	cmpHalf := half.Cmp(div)
+
+	away := false // увеличить |q| на 1
+	switch mode {
+	case Down:
+	case Up:
+		away = true
+	case Floor:
+		away = sign < 0
+	case Ceiling:
+		away = sign > 0
+	case HalfDown:
+		away = cmpHalf > 0
+	case HalfEven:
+		away = cmpHalf > 0 || (cmpHalf == 0 && q.Bit(0) == 1)
+	default: // HalfUp
+		away = cmpHalf >= 0
+	}
+	if away {
+		q.Add(q, big.NewInt(int64(sign)))
+	}
+	return Decimal{coef: q, scale: places}
+}
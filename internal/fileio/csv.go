@@ -13,7 +13,7 @@ import (
 
 // readCSV reads CSV with headerRow (1-based), auto-detecting encoding and converting to UTF-8.
 // It supports UTF-8 and Windows-1251 out of the box.
-func readCSV(r io.Reader, headerRow int) ([]map[string]string, error) {
+func readCSV(r io.Reader, headerRow int) (*Table, error) {
 	br := bufio.NewReader(r)
 
 	// Peek a bit to detect encoding
@@ -54,5 +54,5 @@ func readCSV(r io.Reader, headerRow int) ([]map[string]string, error) {
 		return nil, nil
 	}
 	h := pickHeader(rows, headerRow)
-	return rowsToMaps(rows, h, headerRow), nil
+	return rowsToTable(rows, h, headerRow), nil
 }
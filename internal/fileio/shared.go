@@ -7,6 +7,10 @@ import (
 	"strconv"
 	"strings"
 	"unicode"
+
+	excelize "github.com/xuri/excelize/v2"
+
+	"recon-service/internal/decimal"
 )
 
 // ---------- НОРМАЛИЗАЦИЯ ТЕКСТА (общая для xls/xlsx/csv) ----------
@@ -230,9 +234,36 @@ func isQtyHeader(h string) bool {
 
 // ParseRuFloat — робастный парсер RU/EN чисел: NBSP/узкие пробелы, запятая/точка, скобки-минус, смешанные разделители
 func ParseRuFloat(s string) (float64, bool) {
+	s, ok := cleanRuNumber(s)
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+// ParseRuDecimal — то же, что ParseRuFloat, но без двоичного округления:
+// "1 234,567" → ровно 1234.567
+func ParseRuDecimal(s string) (decimal.Decimal, bool) {
+	s, ok := cleanRuNumber(s)
+	if !ok {
+		return decimal.Decimal{}, false
+	}
+	d, err := decimal.Parse(s)
+	if err != nil {
+		return decimal.Decimal{}, false
+	}
+	return d, true
+}
+
+// cleanRuNumber приводит RU/EN запись числа к виду "-1234.5"
+func cleanRuNumber(s string) (string, bool) {
 	s = strings.TrimSpace(s)
 	if s == "" {
-		return 0, false
+		return "", false
 	}
 	// унификация минуса и пробелов
 	s = strings.ReplaceAll(s, "−", "-")     // U+2212 -> '-'
@@ -271,25 +302,62 @@ func ParseRuFloat(s string) (float64, bool) {
 	}
 	s = sb.String()
 	if s == "" || s == "-" || s == "." || s == "-." {
-		return 0, false
+		return "", false
 	}
+	if neg {
+		if strings.HasPrefix(s, "-") {
+			s = s[1:]
+		} else {
+			s = "-" + s
+		}
+	}
+	return s, true
+}
 
-	f, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		return 0, false
+// ---------- КОНВЕРТАЦИЯ ТАБЛИЦЫ В ЗАПИСИ ----------
+
+// Table — прочитанный лист: записи по заголовкам и их место в файле
+type Table struct {
+	Sheet   string   // имя листа ("" для CSV)
+	Headers []string // итоговые заголовки (после склейки двухъярусной шапки)
+	Records []Record
+
+	cols map[string]int // заголовок → 0-based колонка
+}
+
+// Record — строка данных листа
+type Record struct {
+	Row    int               // 1-based номер строки в листе/файле
+	Values map[string]string // заголовок → значение
+}
+
+// Cell — адрес ячейки ("B12") колонки header в строке row; "" — нет такой колонки
+func (t *Table) Cell(header string, row int) string {
+	c, ok := t.cols[header]
+	if !ok || header == "" || row <= 0 {
+		return ""
 	}
-	if neg {
-		f = -f
+	name, err := excelize.ColumnNumberToName(c + 1)
+	if err != nil {
+		return ""
 	}
-	return f, true
+	return name + itoa(row)
 }
 
-// ---------- КОНВЕРТАЦИЯ ТАБЛИЦЫ В MAPS ----------
+// Maps — только значения записей (формат ReadAnyMaps)
+func (t *Table) Maps() []map[string]string {
+	out := make([]map[string]string, 0, len(t.Records))
+	for _, rec := range t.Records {
+		out = append(out, rec.Values)
+	}
+	return out
+}
 
-// rowsToMaps — конвертирует AoA в []map по заголовкам.
+// rowsToTable — конвертирует AoA в записи по заголовкам.
 // Если строка под шапкой распознана как «второй ярус», начинаем с headerRow+1.
-// Дополнительно: для колонок с количеством/остатком нормализуем число через ParseRuFloat.
-func rowsToMaps(rows [][]string, headers []string, headerRow int) []map[string]string {
+// Дополнительно: для колонок с количеством/остатком нормализуем число через ParseRuDecimal.
+// rows[i] — i-я строка листа, поэтому номер строки записи — i+1.
+func rowsToTable(rows [][]string, headers []string, headerRow int) *Table {
 	idx := headerRow - 1
 	useBot := idx+1 < len(rows) && looksLikeSecondHeaderRow(rows[idx+1])
 
@@ -307,13 +375,15 @@ func rowsToMaps(rows [][]string, headers []string, headerRow int) []map[string]s
 
 	// заранее посчитаем, какие колонки — количественные
 	qtyCol := make(map[int]bool, len(headers))
+	cols := make(map[string]int, len(headers))
 	for i, h := range headers {
 		if isQtyHeader(h) {
 			qtyCol[i] = true
 		}
+		cols[h] = i // при повторе заголовка значение берётся из последней колонки — как и в записи
 	}
 
-	var out []map[string]string
+	t := &Table{Headers: headers, cols: cols}
 	for r := start; r < len(rows); r++ {
 		rec := rows[r]
 		if len(rec) == 0 {
@@ -329,8 +399,8 @@ func rowsToMaps(rows [][]string, headers []string, headerRow int) []map[string]s
 			}
 
 			if qtyCol[c] {
-				if f, ok := ParseRuFloat(v); ok {
-					v = strconv.FormatFloat(f, 'f', -1, 64) // "495558.073" / "118" / "-5"
+				if d, ok := ParseRuDecimal(v); ok {
+					v = d.String() // "495558.073" / "118" / "-5" — без двоичного округления
 				}
 			}
 
@@ -341,25 +411,46 @@ func rowsToMaps(rows [][]string, headers []string, headerRow int) []map[string]s
 		}
 
 		if !empty {
-			out = append(out, m)
+			t.Records = append(t.Records, Record{Row: r + 1, Values: m})
 		}
 	}
-	return out
+	return t
 }
 
-// ReadAnyMaps — выбирает парсер по расширению и возвращает []map[header]value.
-func ReadAnyMaps(r io.Reader, filename string, headerRow int) ([]map[string]string, error) {
+// ReadAnyTable — выбирает парсер по расширению и возвращает записи с
+// номерами строк и именем листа. Пустой файл — пустая таблица, не nil.
+func ReadAnyTable(r io.Reader, filename string, headerRow int) (*Table, error) {
+	var (
+		t   *Table
+		err error
+	)
 	ext := strings.ToLower(filepath.Ext(filename))
 	switch ext {
 	case ".xlsx":
-		return readXLSX(r, headerRow)
+		t, err = readXLSX(r, headerRow)
 	case ".xls":
-		return readXLS(r, headerRow)
+		t, err = readXLS(r, headerRow)
 	case ".csv":
-		return readCSV(r, headerRow)
+		t, err = readCSV(r, headerRow)
 	default:
 		return nil, fmt.Errorf("unsupported file: %s", filename)
 	}
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		t = &Table{}
+	}
+	return t, nil
+}
+
+// ReadAnyMaps — выбирает парсер по расширению и возвращает []map[header]value.
+func ReadAnyMaps(r io.Reader, filename string, headerRow int) ([]map[string]string, error) {
+	t, err := ReadAnyTable(r, filename, headerRow)
+	if err != nil {
+		return nil, err
+	}
+	return t.Maps(), nil
 }
 
 // (на всякий случай)
@@ -48,7 +48,7 @@ func computeMaxCols(sheet *xls.WorkSheet, headerRow int) int {
 	return maxCols
 }
 
-func readXLS(r io.Reader, headerRow int) ([]map[string]string, error) {
+func readXLS(r io.Reader, headerRow int) (*Table, error) {
 	if headerRow <= 0 {
 		return nil, errors.New("headerRow must be 1-based and >= 1")
 	}
@@ -95,7 +95,9 @@ func readXLS(r io.Reader, headerRow int) ([]map[string]string, error) {
 		rows = append(rows, cols)
 	}
 
-	// общий pickHeader объединит верх+низ шапки, затем rowsToMaps соберёт записи
+	// общий pickHeader объединит верх+низ шапки, затем rowsToTable соберёт записи
 	h := pickHeader(rows, headerRow)
-	return rowsToMaps(rows, h, headerRow), nil
+	t := rowsToTable(rows, h, headerRow)
+	t.Sheet = sheet.Name
+	return t, nil
 }
@@ -10,7 +10,7 @@ import (
 
 // readXLSX читает лист XLSX так, чтобы числа приходили сырыми, а формулы — рассчитанными.
 // headerRow — 1-based индекс строки заголовков.
-func readXLSX(r io.Reader, headerRow int) ([]map[string]string, error) {
+func readXLSX(r io.Reader, headerRow int) (*Table, error) {
 	if headerRow <= 0 {
 		headerRow = 1
 	}
@@ -48,7 +48,9 @@ func readXLSX(r io.Reader, headerRow int) ([]map[string]string, error) {
 			}
 		}
 		h := pickHeader(rows, headerRow)
-		return rowsToMaps(rows, h, headerRow), nil
+		t := rowsToTable(rows, h, headerRow)
+		t.Sheet = sheet
+		return t, nil
 	}
 
 	// dim = "A1:K234" → конца координаты
@@ -90,5 +92,7 @@ func readXLSX(r io.Reader, headerRow int) ([]map[string]string, error) {
 	}
 
 	h := pickHeader(rows, headerRow)
-	return rowsToMaps(rows, h, headerRow), nil
+	t := rowsToTable(rows, h, headerRow)
+	t.Sheet = sheet
+	return t, nil
 }
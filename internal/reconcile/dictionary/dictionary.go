@@ -0,0 +1,159 @@
+// Package dictionary — доменные словари нормализации: синонимы, фразовые
+// regex-замены, стоп-слова и алиасы единиц измерения. Словари описываются
+// JSON-файлами и компилируются один раз при загрузке.
+package dictionary
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultName — имя словаря, который используется, если в запросе не указан другой
+const DefaultName = "default"
+
+//go:embed builtin/*.json
+var builtinFS embed.FS
+
+// Source — словарь в том виде, как он лежит в файле
+type Source struct {
+	Name      string            `json:"name"`
+	Synonyms  map[string]string `json:"synonyms"`  // токен → канонический токен
+	Rules     []RuleSource      `json:"rules"`     // фразовые замены до токенизации
+	StopWords []string          `json:"stopWords"` // токены, не влияющие на сущность
+	Units     map[string]string `json:"units"`     // алиас единицы → каноническая (мл → ml)
+}
+
+// RuleSource — regex-замена (синтаксис Go regexp, замена с ${1} и т.п.)
+type RuleSource struct {
+	Pattern string `json:"pattern"`
+	Replace string `json:"replace"`
+}
+
+// Rule — скомпилированная замена
+type Rule struct {
+	Re      *regexp.Regexp
+	Replace string
+}
+
+// Dictionary — скомпилированный словарь; после Compile только читается
+// и безопасен для параллельного использования.
+type Dictionary struct {
+	Name     string
+	Version  int // номер версии в реестре (0 — вне реестра)
+	Source   Source
+	Synonyms map[string]string
+	Rules    []Rule
+	Stop     map[string]struct{}
+	Units    map[string]string
+}
+
+// Compile проверяет и компилирует словарь. Ошибка содержит номер
+// неправильного правила.
+func Compile(src Source) (*Dictionary, error) {
+	name := strings.TrimSpace(src.Name)
+	if name == "" {
+		return nil, fmt.Errorf("dictionary: empty name")
+	}
+	src.Name = name
+	d := &Dictionary{
+		Name:     name,
+		Source:   src,
+		Synonyms: make(map[string]string, len(src.Synonyms)),
+		Stop:     make(map[string]struct{}, len(src.StopWords)),
+		Units:    make(map[string]string, len(src.Units)),
+	}
+	for k, v := range src.Synonyms {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if k == "" {
+			continue
+		}
+		d.Synonyms[k] = strings.ToLower(strings.TrimSpace(v))
+	}
+	for i, rs := range src.Rules {
+		if rs.Pattern == "" {
+			return nil, fmt.Errorf("dictionary %q: rule %d: empty pattern", name, i+1)
+		}
+		re, err := regexp.Compile(rs.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("dictionary %q: rule %d: %w", name, i+1, err)
+		}
+		d.Rules = append(d.Rules, Rule{Re: re, Replace: rs.Replace})
+	}
+	for _, w := range src.StopWords {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			d.Stop[w] = struct{}{}
+		}
+	}
+
+	for k, v := range src.Units {
+		k = strings.ToLower(strings.TrimSpace(k))
+		v = strings.ToLower(strings.TrimSpace(v))
+		if k == "" || v == "" {
+			return nil, fmt.Errorf("dictionary %q: empty unit alias %q→%q", name, k, v)
+		}
+		d.Units[k] = v
+	}
+	return d, nil
+}
+
+// Rewrite применяет фразовые замены по порядку
+func (d *Dictionary) Rewrite(s string) string {
+	for _, r := range d.Rules {
+		s = r.Re.ReplaceAllString(s, r.Replace)
+	}
+	return s
+}
+
+// Synonym возвращает канонический токен (t — в нижнем регистре)
+func (d *Dictionary) Synonym(t string) (string, bool) {
+	v, ok := d.Synonyms[t]
+	return v, ok
+}
+
+// IsStop — стоп-слово ли токен (t — в нижнем регистре)
+func (d *Dictionary) IsStop(t string) bool {
+	_, ok := d.Stop[t]
+	return ok
+}
+
+// IsUnit — является ли токен единицей измерения (t — в нижнем регистре)
+func (d *Dictionary) IsUnit(t string) bool {
+	_, ok := d.Units[t]
+	return ok
+}
+
+// Unit возвращает каноническое имя единицы по алиасу ("мл" → "ml")
+func (d *Dictionary) Unit(alias string) (string, bool) {
+	u, ok := d.Units[alias]
+	return u, ok
+}
+
+// Parse читает и компилирует словарь из JSON
+func Parse(data []byte) (*Dictionary, error) {
+	var src Source
+	if err := json.Unmarshal(data, &src); err != nil {
+		return nil, fmt.Errorf("dictionary: %w", err)
+	}
+	return Compile(src)
+}
+
+// Builtin — встроенный словарь по умолчанию (паллеты/поддоны)
+func Builtin() *Dictionary { return builtin }
+
+var builtin = mustBuiltin()
+
+// mustBuiltin компилирует встроенный словарь (каждый вызов — новый экземпляр)
+func mustBuiltin() *Dictionary {
+	data, err := builtinFS.ReadFile("builtin/" + DefaultName + ".json")
+	if err != nil {
+		panic(err)
+	}
+	d, err := Parse(data)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
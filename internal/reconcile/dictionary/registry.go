@@ -0,0 +1,513 @@
+package dictionary
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrNotFound     = errors.New("dictionary not found")
+	ErrVersion      = errors.New("dictionary version not found")
+	ErrBadName      = errors.New("dictionary name must match [a-z0-9_-]{1,64}")
+	ErrProtected    = errors.New("default dictionary cannot be deleted")
+	ErrNameMismatch = errors.New("dictionary name in body differs from path")
+	ErrExists       = errors.New("dictionary already exists")
+)
+
+// ValidationError — словарь не прошёл проверку (ошибка клиента, а не I/O)
+type ValidationError struct{ Err error }
+
+func (e *ValidationError) Error() string { return e.Err.Error() }
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+var reName = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// VersionInfo — метаданные версии словаря
+type VersionInfo struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Origin    string    `json:"origin"` // builtin | file | api
+	Active    bool      `json:"active"`
+}
+
+// Info — краткое описание словаря для списка
+type Info struct {
+	Name          string `json:"name"`
+	ActiveVersion int    `json:"activeVersion"`
+	Versions      int    `json:"versions"`
+}
+
+type version struct {
+	dict      *Dictionary
+	createdAt time.Time
+	origin    string
+}
+
+// versionFile — версия словаря на диске: <dir>/<name>/<n>.json
+type versionFile struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Origin    string    `json:"origin"`
+	Source    Source    `json:"source"`
+}
+
+// activeFile — указатель на активную версию: <dir>/<name>/active
+const activeFile = "active"
+
+// Registry — словари по имени с нумерованными версиями. Чтение (Get)
+// идёт без блокировок через атомарный снимок активных версий: сверка,
+// уже взявшая словарь, доработает на нём, а новые запросы увидят новый.
+// Если задан каталог, история версий и активная версия хранятся в нём и
+// переживают рестарт — номер версии однозначно определяет словарь.
+type Registry struct {
+	dir     string
+	memOnly error // не nil — каталог недоступен для записи, история только в памяти
+
+	mu            sync.Mutex            // сериализует изменения
+	history       map[string][]*version // name -> версии (1-based: history[n-1])
+	activeVersion map[string]int        // name -> активная версия
+
+	active atomic.Pointer[map[string]*Dictionary]
+}
+
+// Load собирает реестр: сохранённая история версий, встроенный словарь
+// (новой версией, если он изменился с прошлого запуска) и все *.json из
+// dir (если каталог существует). Файл с именем "default" становится
+// следующей версией встроенного словаря.
+//
+// requireStore — историю обязательно писать на диск (включён admin API).
+// Без него недоступный для записи каталог не мешает старту: словари
+// читаются как обычно, версии живут в памяти, причина — в MemoryOnly.
+func Load(dir string, requireStore bool) (*Registry, error) {
+	r := &Registry{
+		dir:           dir,
+		history:       make(map[string][]*version),
+		activeVersion: make(map[string]int),
+	}
+	if dir != "" && !requireStore {
+		r.memOnly = probeWritable(dir)
+	}
+	if err := r.loadHistory(); err != nil {
+		return nil, err
+	}
+	if builtin := mustBuiltin(); !r.hasOrigin(DefaultName, "builtin", builtin.Source) {
+		if err := r.appendVersion(builtin, "builtin"); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	r.publish()
+	return r, nil
+}
+
+// MemoryOnly — почему история версий не пишется на диск (nil — пишется)
+func (r *Registry) MemoryOnly() error { return r.memOnly }
+
+// Get возвращает активную версию словаря; пустое имя — словарь по умолчанию
+func (r *Registry) Get(name string) (*Dictionary, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = DefaultName
+	}
+	d, ok := (*r.active.Load())[name]
+	return d, ok
+}
+
+// Names — имена доступных словарей (для ошибок и UI)
+func (r *Registry) Names() []string {
+	m := *r.active.Load()
+	out := make([]string, 0, len(m))
+	for n := range m {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// List — все словари с активной версией
+func (r *Registry) List() []Info {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := *r.active.Load()
+	out := make([]Info, 0, len(m))
+	for name, d := range m {
+		out = append(out, Info{Name: name, ActiveVersion: d.Version, Versions: len(r.history[name])})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Versions — история версий словаря
+func (r *Registry) Versions(name string) ([]VersionInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	hist, ok := r.history[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	cur, _ := r.Get(name)
+	out := make([]VersionInfo, 0, len(hist))
+	for _, v := range hist {
+		out = append(out, VersionInfo{
+			Version:   v.dict.Version,
+			CreatedAt: v.createdAt,
+			Origin:    v.origin,
+			Active:    cur != nil && cur.Version == v.dict.Version,
+		})
+	}
+	return out, nil
+}
+
+// Version — конкретная версия словаря
+func (r *Registry) Version(name string, n int) (*Dictionary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	hist, ok := r.history[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if n < 1 || n > len(hist) {
+		return nil, ErrVersion
+	}
+	return hist[n-1].dict, nil
+}
+
+// Put проверяет словарь (в т.ч. компилирует regex), сохраняет его как
+// новую версию, делает активной и пишет в каталог словарей.
+func (r *Registry) Put(src Source) (*Dictionary, error) {
+	return r.put(src, false)
+}
+
+// Create — Put только для нового имени; существующее — ErrExists.
+// Проверка и запись идут под одной блокировкой.
+func (r *Registry) Create(src Source) (*Dictionary, error) {
+	return r.put(src, true)
+}
+
+func (r *Registry) put(src Source, create bool) (*Dictionary, error) {
+	src.Name = strings.TrimSpace(src.Name)
+	if !reName.MatchString(src.Name) {
+		return nil, ErrBadName
+	}
+	d, err := Compile(src)
+	if err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.history[d.Name]; exists && create {
+		return nil, ErrExists
+	}
+	if err := r.persist(d); err != nil {
+		return nil, err
+	}
+	if err := r.appendVersion(d, "api"); err != nil {
+		return nil, err
+	}
+	r.publish()
+	return d, nil
+}
+
+// Activate делает активной ранее сохранённую версию (откат)
+func (r *Registry) Activate(name string, n int) (*Dictionary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	hist, ok := r.history[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if n < 1 || n > len(hist) {
+		return nil, ErrVersion
+	}
+	d := hist[n-1].dict
+	if err := r.persist(d); err != nil {
+		return nil, err
+	}
+	if err := r.writeActive(name, n); err != nil {
+		return nil, err
+	}
+	r.activeVersion[name] = n
+	r.publish()
+	return d, nil
+}
+
+// Delete убирает словарь из реестра и каталога (встроенный удалить нельзя)
+func (r *Registry) Delete(name string) error {
+	if name == DefaultName {
+		return ErrProtected
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.history[name]; !ok {
+		return ErrNotFound
+	}
+	if r.store() {
+		if err := os.Remove(filepath.Join(r.dir, name+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err := os.RemoveAll(filepath.Join(r.dir, name)); err != nil {
+			return err
+		}
+	}
+	delete(r.history, name)
+	delete(r.activeVersion, name)
+	r.publish()
+	return nil
+}
+
+// Reload перечитывает каталог словарей: изменившиеся файлы становятся
+// новыми активными версиями. Возвращает имена обновлённых словарей.
+func (r *Registry) Reload() ([]string, error) {
+	if r.dir == "" {
+		return nil, nil
+	}
+	files, err := filepath.Glob(filepath.Join(r.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	// сначала компилируем всё: ошибка в одном файле не должна оставить
+	// реестр наполовину обновлённым
+	dicts := make([]*Dictionary, 0, len(files))
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		d, err := Parse(data)
+		if err != nil {
+			return nil, &ValidationError{Err: fmt.Errorf("%s: %w", f, err)}
+		}
+		dicts = append(dicts, d)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var changed []string
+	for _, d := range dicts {
+		if cur := r.current(d.Name); cur != nil && sameSource(cur.Source, d.Source) {
+			continue
+		}
+		if err := r.appendVersion(d, "file"); err != nil {
+			return nil, err
+		}
+		changed = append(changed, d.Name)
+	}
+	if len(changed) > 0 {
+		r.publish()
+	}
+	return changed, nil
+}
+
+// ---------- внутреннее (под r.mu) ----------
+
+// appendVersion нумерует словарь следующей версией, сохраняет её в
+// историю на диске и делает активной
+func (r *Registry) appendVersion(d *Dictionary, origin string) error {
+	v := &version{dict: d, createdAt: time.Now().UTC(), origin: origin}
+	n := len(r.history[d.Name]) + 1
+	if err := r.writeVersion(d.Name, n, v); err != nil {
+		return err
+	}
+	if err := r.writeActive(d.Name, n); err != nil {
+		return err
+	}
+	d.Version = n
+	r.history[d.Name] = append(r.history[d.Name], v)
+	r.activeVersion[d.Name] = n
+	return nil
+}
+
+// hasOrigin — есть ли в истории name версия с таким источником и содержимым
+func (r *Registry) hasOrigin(name, origin string, src Source) bool {
+	hist := r.history[name]
+	for i := len(hist) - 1; i >= 0; i-- {
+		if hist[i].origin == origin {
+			return sameSource(hist[i].dict.Source, src)
+		}
+	}
+	return false
+}
+
+func (r *Registry) current(name string) *Dictionary {
+	n, ok := r.activeVersion[name]
+	if !ok {
+		return nil
+	}
+	return r.history[name][n-1].dict
+}
+
+// publish атомарно подменяет снимок активных версий
+func (r *Registry) publish() {
+	m := make(map[string]*Dictionary, len(r.history))
+	for name := range r.history {
+		m[name] = r.current(name)
+	}
+	r.active.Store(&m)
+}
+
+// store — пишем ли изменения в каталог
+func (r *Registry) store() bool { return r.dir != "" && r.memOnly == nil }
+
+// persist пишет словарь в каталог (через временный файл + rename)
+func (r *Registry) persist(d *Dictionary) error {
+	if !r.store() {
+		return nil
+	}
+	data, err := json.MarshalIndent(d.Source, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeAtomic(r.dir, d.Name+".json", append(data, '\n'))
+}
+
+// writeVersion сохраняет версию n словаря name в <dir>/<name>/<n>.json
+func (r *Registry) writeVersion(name string, n int, v *version) error {
+	if !r.store() {
+		return nil
+	}
+	data, err := json.MarshalIndent(versionFile{
+		Version:   n,
+		CreatedAt: v.createdAt,
+		Origin:    v.origin,
+		Source:    v.dict.Source,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeAtomic(filepath.Join(r.dir, name), strconv.Itoa(n)+".json", append(data, '\n'))
+}
+
+// writeActive сохраняет номер активной версии в <dir>/<name>/active
+func (r *Registry) writeActive(name string, n int) error {
+	if !r.store() {
+		return nil
+	}
+	return writeAtomic(filepath.Join(r.dir, name), activeFile, []byte(strconv.Itoa(n)+"\n"))
+}
+
+// loadHistory читает сохранённые версии из <dir>/<name>/ (до Reload).
+// Номера версий должны идти подряд с 1 — иначе история испорчена, и
+// молча перенумеровать её значило бы выдать старый номер другому словарю.
+func (r *Registry) loadHistory() error {
+	if r.dir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(r.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.IsDir() || !reName.MatchString(e.Name()) {
+			continue
+		}
+		name := e.Name()
+		files, err := filepath.Glob(filepath.Join(r.dir, name, "*.json"))
+		if err != nil {
+			return err
+		}
+		vs := make(map[int]*version, len(files))
+		for _, f := range files {
+			n, err := strconv.Atoi(strings.TrimSuffix(filepath.Base(f), ".json"))
+			if err != nil || n < 1 {
+				continue
+			}
+			data, err := os.ReadFile(f)
+			if err != nil {
+				return err
+			}
+			var vf versionFile
+			if err := json.Unmarshal(data, &vf); err != nil {
+				return fmt.Errorf("%s: %w", f, err)
+			}
+			vf.Source.Name = name
+			d, err := Compile(vf.Source)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f, err)
+			}
+			d.Version = n
+			vs[n] = &version{dict: d, createdAt: vf.CreatedAt, origin: vf.Origin}
+		}
+		if len(vs) == 0 {
+			continue
+		}
+		hist := make([]*version, len(vs))
+		for n, v := range vs {
+			if n > len(vs) {
+				return fmt.Errorf("%s: version files must be numbered 1..%d, found %d", filepath.Join(r.dir, name), len(vs), n)
+			}
+			hist[n-1] = v
+		}
+		r.history[name] = hist
+
+		active := len(hist)
+		if data, err := os.ReadFile(filepath.Join(r.dir, name, activeFile)); err == nil {
+			if n, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && n >= 1 && n <= len(hist) {
+				active = n
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		r.activeVersion[name] = active
+	}
+	return nil
+}
+
+// probeWritable проверяет, что в dir можно создавать файлы (создаёт
+// каталог при необходимости)
+func probeWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".probe-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// writeAtomic пишет файл через временный файл + rename
+func writeAtomic(dir, name string, data []byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+name+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+func sameSource(a, b Source) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Equal(ja, jb)
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+
+	"recon-service/internal/reconcile/dictionary"
+)
+
+// DictionaryAdmin — CRUD словарей нормализации для /admin/dictionaries.
+// Изменения проходят валидацию (компиляция regex и т.п.), каждое сохранение
+// даёт новую нумерованную версию; активная версия подменяется атомарно.
+type DictionaryAdmin struct {
+	Reg    *dictionary.Registry
+	Logger zerolog.Logger
+}
+
+// dictionaryView — словарь в ответах admin API
+type dictionaryView struct {
+	Name    string            `json:"name"`
+	Version int               `json:"version"`
+	Source  dictionary.Source `json:"source"`
+}
+
+func viewOf(d *dictionary.Dictionary) dictionaryView {
+	return dictionaryView{Name: d.Name, Version: d.Version, Source: d.Source}
+}
+
+// List — GET /admin/dictionaries
+func (a DictionaryAdmin) List(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, a.Reg.List())
+}
+
+// Get — GET /admin/dictionaries/{name} (активная версия)
+func (a DictionaryAdmin) Get(w http.ResponseWriter, r *http.Request) {
+	d, ok := a.Reg.Get(chi.URLParam(r, "name"))
+	if !ok {
+		http.Error(w, dictionary.ErrNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, viewOf(d))
+}
+
+// Create — POST /admin/dictionaries (имя берётся из тела)
+func (a DictionaryAdmin) Create(w http.ResponseWriter, r *http.Request) {
+	var src dictionary.Source
+	if err := json.NewDecoder(r.Body).Decode(&src); err != nil {
+		http.Error(w, "bad json: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.save(w, a.Reg.Create, src, http.StatusCreated)
+}
+
+// Put — PUT /admin/dictionaries/{name}: новая версия словаря
+func (a DictionaryAdmin) Put(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	var src dictionary.Source
+	if err := json.NewDecoder(r.Body).Decode(&src); err != nil {
+		http.Error(w, "bad json: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if src.Name == "" {
+		src.Name = name
+	}
+	if src.Name != name {
+		http.Error(w, dictionary.ErrNameMismatch.Error(), http.StatusBadRequest)
+		return
+	}
+	a.save(w, a.Reg.Put, src, http.StatusOK)
+}
+
+// save сохраняет словарь через put (Reg.Put или Reg.Create): ошибки
+// валидации — 422, занятое имя — 409, сбой записи на диск — 500
+func (a DictionaryAdmin) save(w http.ResponseWriter, put func(dictionary.Source) (*dictionary.Dictionary, error), src dictionary.Source, status int) {
+	d, err := put(src)
+	if err != nil {
+		if statusOf(err) == http.StatusInternalServerError {
+			a.Logger.Error().Err(err).Str("dictionary", src.Name).Msg("dictionary save")
+		}
+		http.Error(w, err.Error(), statusOf(err))
+		return
+	}
+	a.Logger.Info().Str("dictionary", d.Name).Int("version", d.Version).Msg("dictionary saved")
+	writeJSON(w, status, viewOf(d))
+}
+
+// Delete — DELETE /admin/dictionaries/{name}
+func (a DictionaryAdmin) Delete(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if err := a.Reg.Delete(name); err != nil {
+		http.Error(w, err.Error(), statusOf(err))
+		return
+	}
+	a.Logger.Info().Str("dictionary", name).Msg("dictionary deleted")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Versions — GET /admin/dictionaries/{name}/versions
+func (a DictionaryAdmin) Versions(w http.ResponseWriter, r *http.Request) {
+	vs, err := a.Reg.Versions(chi.URLParam(r, "name"))
+	if err != nil {
+		http.Error(w, err.Error(), statusOf(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, vs)
+}
+
+// Version — GET /admin/dictionaries/{name}/versions/{version}
+func (a DictionaryAdmin) Version(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		http.Error(w, "bad version", http.StatusBadRequest)
+		return
+	}
+	d, err := a.Reg.Version(chi.URLParam(r, "name"), n)
+	if err != nil {
+		http.Error(w, err.Error(), statusOf(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, viewOf(d))
+}
+
+// Rollback — POST /admin/dictionaries/{name}/versions/{version}/activate
+func (a DictionaryAdmin) Rollback(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	n, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		http.Error(w, "bad version", http.StatusBadRequest)
+		return
+	}
+	d, err := a.Reg.Activate(name, n)
+	if err != nil {
+		http.Error(w, err.Error(), statusOf(err))
+		return
+	}
+	a.Logger.Info().Str("dictionary", name).Int("version", n).Msg("dictionary activated")
+	writeJSON(w, http.StatusOK, viewOf(d))
+}
+
+// Reload — POST /admin/dictionaries/reload: перечитать каталог словарей
+func (a DictionaryAdmin) Reload(w http.ResponseWriter, _ *http.Request) {
+	changed, err := a.Reg.Reload()
+	if err != nil {
+		http.Error(w, err.Error(), statusOf(err))
+		return
+	}
+	if changed == nil {
+		changed = []string{}
+	}
+	a.Logger.Info().Strs("changed", changed).Msg("dictionaries reloaded")
+	writeJSON(w, http.StatusOK, map[string]any{"changed": changed})
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, dictionary.ErrNotFound), errors.Is(err, dictionary.ErrVersion):
+		return http.StatusNotFound
+	case errors.Is(err, dictionary.ErrProtected):
+		return http.StatusForbidden
+	case errors.Is(err, dictionary.ErrExists):
+		return http.StatusConflict
+	case errors.Is(err, dictionary.ErrBadName), errors.As(err, new(*dictionary.ValidationError)):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
@@ -2,23 +2,25 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"math"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
-	"regexp"
 	"github.com/rs/zerolog"
 
 	"recon-service/internal/config"
+	"recon-service/internal/decimal"
 	"recon-service/internal/fileio"
+	"recon-service/internal/reconcile/dictionary"
 	"recon-service/internal/reconcile/model"
 	recSvc "recon-service/internal/reconcile/service"
 )
 
 // Reconcile возвращает http.HandlerFunc, чтобы вы могли вызвать его как
-// r.Post("/reconcile", recHnd.Reconcile(cfg, logger)) в роутере.
-func  Reconcile(cfg config.Config, logger zerolog.Logger) http.HandlerFunc {
+// r.Post("/reconcile", recHnd.Reconcile(cfg, logger, dicts)) в роутере.
+func  Reconcile(cfg config.Config, logger zerolog.Logger, dicts *dictionary.Registry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
@@ -55,12 +57,12 @@ func  Reconcile(cfg config.Config, logger zerolog.Logger) http.HandlerFunc {
 		defer fileB.Close()
 
 		// Читаем таблицы (auto-encoding CSV, XLS/XLSX и т.д. внутри fileio)
-		rowsA, err := fileio.ReadAnyMaps(fileA, headerA.Filename, atoi(r.FormValue("a_header_row"), 1))
+		tblA, err := fileio.ReadAnyTable(fileA, headerA.Filename, atoi(r.FormValue("a_header_row"), 1))
 		if err != nil {
 			http.Error(w, "failed to read A: "+err.Error(), http.StatusBadRequest)
 			return
 		}
-		rowsB, err := fileio.ReadAnyMaps(fileB, headerB.Filename, atoi(r.FormValue("b_header_row"), 1))
+		tblB, err := fileio.ReadAnyTable(fileB, headerB.Filename, atoi(r.FormValue("b_header_row"), 1))
 		if err != nil {
 			http.Error(w, "failed to read B: "+err.Error(), http.StatusBadRequest)
 			return
@@ -73,6 +75,7 @@ func  Reconcile(cfg config.Config, logger zerolog.Logger) http.HandlerFunc {
 			SkuKey:    r.FormValue("a_sku"),
 			UseSku:    toBool(r.FormValue("a_use_sku"), true), // осознанный дефолт
 			HeaderRow: atoi(r.FormValue("a_header_row"), 1),
+			Sku:       toSkuRules(r, "a"),
 		}
 		mb := model.Mapping{
 			NameKey:   r.FormValue("b_name"),
@@ -80,6 +83,38 @@ func  Reconcile(cfg config.Config, logger zerolog.Logger) http.HandlerFunc {
 			SkuKey:    r.FormValue("b_sku"),
 			UseSku:    toBool(r.FormValue("b_use_sku"), true), // осознанный дефолт
 			HeaderRow: atoi(r.FormValue("b_header_row"), 1),
+			Sku:       toSkuRules(r, "b"),
+		}
+
+		// Правила артикулов (регулярки проверяем здесь — 400, а не тихий пропуск)
+		skuA, err := recSvc.NewSkuNormalizer(ma.Sku)
+		if err != nil {
+			http.Error(w, "bad a_sku rules: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		skuB, err := recSvc.NewSkuNormalizer(mb.Sku)
+		if err != nil {
+			http.Error(w, "bad b_sku rules: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Таблица соответствия артикулов A → B (необязательный третий файл)
+		var crosswalk []model.CrosswalkEntry
+		if fileCw, headerCw, err := r.FormFile("crosswalk"); err == nil {
+			defer fileCw.Close()
+			tblCw, err := fileio.ReadAnyTable(fileCw, headerCw.Filename, atoi(r.FormValue("cw_header_row"), 1))
+			if err != nil {
+				http.Error(w, "failed to read crosswalk: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			crosswalk, err = toCrosswalk(tblCw, r.FormValue("cw_a"), r.FormValue("cw_b"), skuA, skuB)
+			if err != nil {
+				http.Error(w, "bad crosswalk: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		} else if err != http.ErrMissingFile {
+			http.Error(w, "bad crosswalk: "+err.Error(), http.StatusBadRequest)
+			return
 		}
 
 		// Опции (дефолты чекбоксов = false)
@@ -90,24 +125,96 @@ opt := model.Options{
     StripUnits:      toBool(r.FormValue("strip_units"), false),
     Unify:           toBool(r.FormValue("unify"), true),
     Lowercase:       toBool(r.FormValue("lowercase"), true),
+    Stemming:        toBool(r.FormValue("stemming"), false),
+    DimSort:         toBool(r.FormValue("dim_sort"), true),
+    Dim3D:           toBool(r.FormValue("dim_3d"), false),
     EnableFuzzy:     toBool(r.FormValue("enable_fuzzy"), true) ||
                      toBool(r.FormValue("fuzzy"), true) ||
                      toBool(r.FormValue("fuzzy_search"), true),
     StrictAfterNorm: toBool(r.FormValue("strict_after_norm"), false),
     Threshold:       toFloat(r.FormValue("threshold"), 0.83),
+    TopK:            atoi(r.FormValue("top_k"), 50),
+    TieEpsilon:      toFloat(r.FormValue("tie_epsilon"), 0.02),
+    QtyTolAbs:       toQty(r.FormValue("qty_tol_abs")),
+    QtyTolRel:       toRatio(r.FormValue("qty_tol_rel")),
+    QtyPrecision:    atoi(r.FormValue("qty_precision"), -1),
+    Metric:          strings.ToLower(strings.TrimSpace(r.FormValue("metric"))),
+    SkuNameFloor:    toFloat(r.FormValue("sku_name_floor"), 0.5),
+    Aggregate:       strings.ToLower(strings.TrimSpace(r.FormValue("aggregate"))),
+    Explain:         toBool(r.FormValue("explain"), false),
+    ExplainTop:      atoi(r.FormValue("explain_top"), 5),
+    Suggest:         atoi(r.FormValue("suggest"), 0),
+    SuggestMin:      toFloat(r.FormValue("suggest_min"), 0.5),
 }
 
+		// Метрика схожести (пусто — damerau) и веса для metric=weighted
+		if !recSvc.ValidMetric(opt.Metric) {
+			http.Error(w, "unknown metric: "+opt.Metric+
+				" (available: "+strings.Join(recSvc.Metrics(), ", ")+")", http.StatusBadRequest)
+			return
+		}
+		if opt.Metric == "" {
+			opt.Metric = recSvc.MetricDamerau
+		}
+		if opt.MetricWeights, err = toWeights(r.FormValue("metric_weights")); err != nil {
+			http.Error(w, "bad metric_weights: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Режим назначения пар (пусто — greedy)
+		assignment, ok := toAssignment(r.FormValue("assignment"))
+		if !ok {
+			http.Error(w, "unknown assignment: "+r.FormValue("assignment")+
+				" (available: greedy, optimal)", http.StatusBadRequest)
+			return
+		}
+		opt.Assignment = assignment
+
+		// Агрегация дублей (пусто — auto: SKU, иначе имя)
+		if !recSvc.ValidAggregation(opt.Aggregate) {
+			http.Error(w, "unknown aggregate: "+opt.Aggregate+
+				" (available: "+strings.Join(recSvc.Aggregations(), ", ")+")", http.StatusBadRequest)
+			return
+		}
+		if opt.Aggregate == "" {
+			opt.Aggregate = recSvc.AggregateAuto
+		}
+
+		// Округление количеств (режим проверяем, точность < 0 — как в файле)
+		mode, ok := decimal.ParseMode(r.FormValue("qty_rounding"))
+		if !ok {
+			modes := make([]string, 0, len(decimal.Modes()))
+			for _, m := range decimal.Modes() {
+				modes = append(modes, string(m))
+			}
+			http.Error(w, "unknown qty_rounding: "+r.FormValue("qty_rounding")+
+				" (available: "+strings.Join(modes, ", ")+")", http.StatusBadRequest)
+			return
+		}
+		opt.QtyRounding = mode
+
+		// Словарь нормализации (пусто — словарь по умолчанию)
+		dict, ok := dicts.Get(r.FormValue("dictionary"))
+		if !ok {
+			http.Error(w, "unknown dictionary: "+r.FormValue("dictionary")+
+				" (available: "+strings.Join(dicts.Names(), ", ")+")", http.StatusBadRequest)
+			return
+		}
+		opt.Dictionary = dict.Name
+		opt.Dict = dict
+		opt.Crosswalk = crosswalk
+
 
 		// В модельные строки + фильтр шапок
-		aRows := toRowsFiltered(rowsA, ma)
-		bRows := toRowsFiltered(rowsB, mb)
+		aRows := toRowsFiltered(tblA, ma, "A", skuA)
+		bRows := toRowsFiltered(tblB, mb, "B", skuB)
 if debug {
     // статистика распарсенных количеств в B
     gt0, eq0, lt0 := 0, 0, 0
     for _, r := range bRows {
         switch {
-        case r.Qty > 0: gt0++
-        case r.Qty < 0: lt0++
+        case r.Qty.Sign() > 0: gt0++
+        case r.Qty.Sign() < 0: lt0++
         default: eq0++
         }
     }
@@ -115,10 +222,11 @@ if debug {
     // покажем, какие реальные ключи мы использовали для маппинга в B
     // (берём по первой сырой записи rowsB, чтобы увидеть совпадение имён)
     bNameKeyResolved, bQtyKeyResolved := "", ""
-    if len(rowsB) > 0 {
-        bNameKeyResolved = resolveKey(rowsB[0], ma.NameKey) // опечатка была бы — но мы хотим mb
-        bNameKeyResolved = resolveKey(rowsB[0], mb.NameKey)
-        bQtyKeyResolved  = resolveKey(rowsB[0], mb.QtyKey)
+    if len(tblB.Records) > 0 {
+        recB := tblB.Records[0].Values
+        bNameKeyResolved = resolveKey(recB, ma.NameKey) // опечатка была бы — но мы хотим mb
+        bNameKeyResolved = resolveKey(recB, mb.NameKey)
+        bQtyKeyResolved  = resolveKey(recB, mb.QtyKey)
     }
 
     // небольшой сэмпл уже нормализованных строк B
@@ -206,28 +314,67 @@ func toFloat(s string, def float64) float64 {
 	return f
 }
 
-func toNumber(s string) float64 {
-    s = strings.TrimSpace(s)
-    // вычистим спец-пробелы
-    s = strings.Map(func(r rune) rune {
-        switch r {
-        case '\u00A0', '\u2009', '\u202F': // NBSP, thin space, narrow NBSP
-            return -1
-        default:
-            return r
-        }
-    }, s)
-    neg := false
-    if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
-        neg = true
-        s = s[1:len(s)-1]
-    }
-    s = strings.ReplaceAll(s, " ", "")
-    s = strings.ReplaceAll(s, ",", ".")
-    // убираем всё, что не цифра/точка/минус
-    s = regexp.MustCompile(`[^0-9.\-]`).ReplaceAllString(s, "")
-    v, err := strconv.ParseFloat(s, 64)
-    if err != nil { return 0 }
-    if neg { v = -v }
-    return v
+// toRatio: "0.01" или "1%" → ровно 0.01
+func toRatio(s string) decimal.Decimal {
+	s = strings.TrimSpace(s)
+	if p, ok := strings.CutSuffix(s, "%"); ok {
+		return toQty(p).Mul(decimal.New(1, 2))
+	}
+	return toQty(s)
 }
+
+// toSkuRules — правила артикулов стороны side ("a" | "b"): a_sku_fold, a_sku_zeros …
+func toSkuRules(r *http.Request, side string) model.SkuRules {
+	return model.SkuRules{
+		Homoglyphs: toBool(r.FormValue(side+"_sku_homoglyphs"), false),
+		Prefix:     r.FormValue(side + "_sku_prefix"),
+		Suffix:     r.FormValue(side + "_sku_suffix"),
+		Fold:       toBool(r.FormValue(side+"_sku_fold"), false),
+		StripSeps:  toBool(r.FormValue(side+"_sku_seps"), false),
+		TrimZeros:  toBool(r.FormValue(side+"_sku_zeros"), false),
+	}
+}
+
+// toQty — количество из ячейки/поля как точное десятичное (мусор → 0)
+func toQty(s string) decimal.Decimal {
+	d, _ := fileio.ParseRuDecimal(s)
+	return d
+}
+
+// toAssignment: greedy (по умолчанию) | optimal; false — неизвестный режим
+func toAssignment(s string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "greedy":
+		return "greedy", true
+	case "optimal", "global", "hungarian":
+		return "optimal", true
+	default:
+		return "", false
+	}
+}
+
+// toWeights: "damerau=0.5,token_set=0.5" → веса метрик
+func toWeights(s string) (map[string]float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	out := make(map[string]float64)
+	for _, part := range strings.Split(s, ",") {
+		name, val, ok := strings.Cut(part, "=")
+		if !ok {
+			name, val, ok = strings.Cut(part, ":")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || name == "" || name == recSvc.MetricWeighted || !recSvc.ValidMetric(name) {
+			return nil, fmt.Errorf("bad weight %q", part)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil || v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("bad weight %q", part)
+		}
+		out[name] = v
+	}
+	return out, nil
+}
+
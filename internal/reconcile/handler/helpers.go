@@ -1,12 +1,15 @@
 // нормализуем имя колонки: нижний регистр, убираем служ.символы/множественные пробелы/ё→е
 package handler
 import (
+	"fmt"
 
 	"strings"
 
 	"regexp"
 
+	"recon-service/internal/fileio"
 	"recon-service/internal/reconcile/model"
+	recSvc "recon-service/internal/reconcile/service"
 
 )
 
@@ -80,9 +83,13 @@ func resolveKey(rec map[string]string, want string) string {
 
 func max(a, b int) int { if a > b { return a }; return b }
 
-func toRowsFiltered(maps []map[string]string, m model.Mapping) []model.Row {
-	rows := make([]model.Row, 0, len(maps))
-	for _, rec := range maps {
+// toRowsFiltered — записи таблицы в модельные строки (side — "A" или "B");
+// каждая строка помнит лист, номер строки и адреса своих ячеек, а артикул
+// для сравнения приводится правилами стороны (skuNorm).
+func toRowsFiltered(t *fileio.Table, m model.Mapping, side string, skuNorm *recSvc.SkuNormalizer) []model.Row {
+	rows := make([]model.Row, 0, len(t.Records))
+	for _, tr := range t.Records {
+		rec := tr.Values
 		// пропуск явных шапок
 		if looksLikeHeaderMap(rec) {
 			continue
@@ -97,7 +104,7 @@ func toRowsFiltered(maps []map[string]string, m model.Mapping) []model.Row {
 			continue
 		}
 
-		qty := toNumber(rec[qtyKey])
+		qty := toQty(rec[qtyKey])
 
 		sku := ""
 		if m.UseSku && skuKey != "" {
@@ -105,10 +112,60 @@ func toRowsFiltered(maps []map[string]string, m model.Mapping) []model.Row {
 		}
 
 		// отсечь полностью пустые строки
-		if name == "" && sku == "" && qty == 0 {
+		if name == "" && sku == "" && qty.IsZero() {
 			continue
 		}
-		rows = append(rows, model.Row{Name: name, Sku: sku, Qty: qty})
+
+		cells := model.Cells{Name: t.Cell(nameKey, tr.Row), Qty: t.Cell(qtyKey, tr.Row)}
+		if m.UseSku {
+			cells.Sku = t.Cell(skuKey, tr.Row)
+		}
+		rows = append(rows, model.Row{Name: name, Sku: sku, SkuNorm: skuNorm.Normalize(sku), Qty: qty, Src: model.Source{
+			Side:  side,
+			Sheet: t.Sheet,
+			Rows:  []int{tr.Row},
+			Cells: []model.Cells{cells},
+		}})
 	}
 	return rows
 }
+
+// toCrosswalk — записи таблицы соответствия: колонки keyA/keyB (resolveKey,
+// пусто — первые две колонки файла). Артикулы приводятся правилами своих
+// сторон, строки без одного из артикулов пропускаются.
+func toCrosswalk(t *fileio.Table, keyA, keyB string, skuA, skuB *recSvc.SkuNormalizer) ([]model.CrosswalkEntry, error) {
+	if len(t.Records) == 0 {
+		return nil, nil
+	}
+	rec := t.Records[0].Values
+	colA, colB := resolveKey(rec, keyA), resolveKey(rec, keyB)
+	if strings.TrimSpace(keyA) == "" && len(t.Headers) > 0 {
+		colA = t.Headers[0]
+	}
+	if strings.TrimSpace(keyB) == "" && len(t.Headers) > 1 {
+		colB = t.Headers[1]
+	}
+	if colA == "" || colB == "" || colA == colB {
+		return nil, fmt.Errorf("need two distinct columns (cw_a=%q, cw_b=%q)", keyA, keyB)
+	}
+
+	out := make([]model.CrosswalkEntry, 0, len(t.Records))
+	for _, tr := range t.Records {
+		if looksLikeHeaderMap(tr.Values) {
+			continue
+		}
+		a := strings.TrimSpace(tr.Values[colA])
+		b := strings.TrimSpace(tr.Values[colB])
+		if a == "" || b == "" {
+			continue
+		}
+		out = append(out, model.CrosswalkEntry{
+			Row:   tr.Row,
+			SkuA:  a,
+			SkuB:  b,
+			NormA: skuA.Normalize(a),
+			NormB: skuB.Normalize(b),
+		})
+	}
+	return out, nil
+}
@@ -1,11 +1,30 @@
 package model
 
+import (
+	"recon-service/internal/decimal"
+	"recon-service/internal/reconcile/dictionary"
+)
+
 type Mapping struct {
 	NameKey   string // имя колонки с наименованием
 	QtyKey    string // имя колонки с количеством
 	SkuKey    string // имя колонки с артикулом (опционально)
 	UseSku    bool   // использовать ли артикул
 	HeaderRow int    // строка заголовков (1-based)
+	Sku       SkuRules // как приводить артикулы этой стороны перед сравнением
+}
+
+// SkuRules — нормализация артикула одной стороны. Разные ERP пишут один
+// код по-разному ("00123", "ART-123", "art 123"); правила применяются в
+// порядке полей (Fold делает Prefix/Suffix нечувствительными к регистру),
+// сырой Row.Sku в отчёте не меняется.
+type SkuRules struct {
+	Prefix     string // регулярка префикса для удаления (по исходному тексту), якорится к началу: "ART|АРТ"
+	Suffix     string // регулярка суффикса для удаления, якорится к концу: "-(OLD|NEW)"
+	Fold       bool   // регистр не важен (к верхнему)
+	Homoglyphs bool   // кириллические двойники латиницы → латиница (А→A, С→C, Р→P …)
+	StripSeps  bool   // убрать пробелы и разделители - _ . / \
+	TrimZeros  bool   // ведущие нули: "00123" → "123" ("000" → "0")
 }
 
 type Options struct {
@@ -14,34 +33,262 @@ type Options struct {
 	StripUnits      bool    // срезать единицы измерения в конце
 	Unify           bool    // латиница→кириллица (двойники A/А, P/Р и т.п.)
 	Lowercase       bool    // привести к нижнему регистру
+	Stemming        bool    // стемминг слов (Snowball ru/en): "поддоны" == "поддон"
+	DimSort         bool    // габариты без учёта порядка граней (800x1200 == 1200x800)
+	Dim3D           bool    // учитывать третью грань габарита (1200x800x144)
 	EnableFuzzy     bool    // включить нечеткое сопоставление, если нет точного
 	Threshold       float64 // порог схожести для fuzzy (0..1)
+	TieEpsilon      float64 // оценки ближе eps считаются ничьей: пара помечается ambiguous
+	QtyTolAbs       decimal.Decimal // абсолютный допуск по количеству (0 — нет)
+	QtyTolRel       decimal.Decimal // относительный допуск, доля от большего количества (0.01 = 1%)
+	QtyPrecision    int             // знаков после запятой у количеств (< 0 — как в файле, без округления)
+	QtyRounding     decimal.RoundingMode // режим округления количеств (half_up по умолчанию)
+	TopK            int     // сколько кандидатов по триграммам оценивать на строку A (<= 0 — все)
+	Metric          string  // метрика схожести: damerau | jaro_winkler | token_set | tfidf | idf | weighted
+	MetricWeights   map[string]float64 // веса метрик для metric=weighted (пусто — по умолчанию)
 	StrictAfterNorm bool    // только точные совпадения после нормализации (без fuzzy)
+	Assignment      string  // greedy (по умолчанию) | optimal — глобальное назначение A↔B
+	SkuNameFloor    float64 // SKU-пара с bestSimilarity имён ниже — конфликт (0 — 0.5, < 0 — не проверять)
+	Aggregate       string  // auto (по умолчанию: SKU, иначе имя) | none | sku | name | sku+name — как сливать дубли
+	Explain         bool    // приложить к строкам кандидатов и причины отказа
+	ExplainTop      int     // сколько кандидатов показывать (<= 0 — 5)
+	Suggest         int     // сколько подсказок из другой таблицы давать строкам OnlyA/OnlyB (0 — выкл.)
+	SuggestMin      float64 // минимальная схожесть подсказки (<= 0 — 0.5)
+	Dictionary      string  // имя словаря нормализации (synonyms/rules/stop/units)
+
+	Dict *dictionary.Dictionary `json:"-"` // скомпилированный словарь (подставляет handler)
+	Crosswalk []CrosswalkEntry `json:"-"` // таблица соответствия артикулов A → B (подставляет handler)
 }
 
 type Row struct {
+	ID       int     // стабильный номер строки после агрегации (позиция в своей таблице)
 	Name     string  // исходное наименование
 	Sku      string  // артикул
-	Qty      float64 // количество
+	Qty      decimal.Decimal // количество (точное десятичное)
 	NameNorm string  // нормализованное имя (считается для «таблицы B»)
+	SkuNorm  string  // артикул для сравнения (Mapping.Sku; пусто — TrimSpace(Sku))
+	Src      Source  // откуда строка в файле (после агрегации — все исходные строки)
+	Parts    []Part  // слитые дубли (nil — строка не агрегат)
+}
+
+// Part — исходная строка, вошедшая в агрегат
+type Part struct {
+	Row  int             `json:"row,omitempty"` // 1-based строка листа (0 — неизвестна)
+	Name string          `json:"name"`          // наименование как в файле
+	Sku  string          `json:"sku"`
+	Qty  decimal.Decimal `json:"qty"`
+}
+
+// DuplicateGroup — группа строк одной таблицы, слитых aggregate в одну
+type DuplicateGroup struct {
+	Side  string          `json:"side"` // A | B
+	ID    int             `json:"id"`   // Row.ID строки-агрегата
+	By    string          `json:"by"`   // sku | name | sku+name — по какому ключу слиты
+	Sku   string          `json:"sku"`
+	Key   string          `json:"key"` // нормализованное имя первой строки
+	Qty   decimal.Decimal `json:"qty"` // сумма
+	Parts []Part          `json:"parts"`
+}
+
+// Source — происхождение строки: файл, лист, строки и ячейки
+type Source struct {
+	Side  string  `json:"side,omitempty"`  // A | B
+	Sheet string  `json:"sheet,omitempty"` // лист ("" для CSV)
+	Rows  []int   `json:"rows,omitempty"`  // 1-based номера строк листа
+	Cells []Cells `json:"cells,omitempty"` // адреса ячеек, по одному на элемент Rows
+}
+
+// Cells — адреса ячеек наименования, артикула и количества ("B12"; "" — колонки нет)
+type Cells struct {
+	Name string `json:"name,omitempty"`
+	Sku  string `json:"sku,omitempty"`
+	Qty  string `json:"qty,omitempty"`
 }
 
 type ResultRow struct {
+	IDA    int      `json:"idA"` // Row.ID строки A
+	IDB    int      `json:"idB"` // Row.ID строки B
 	Name   string   `json:"name"`
+	NameB  string   `json:"nameB"` // исходное наименование B
+	KeyA   string   `json:"keyA"`  // ключ A, по которому матчили (после стемминга, если включён)
+	KeyB   string   `json:"keyB"`  // ключ B
 	Sku    string   `json:"sku"`
-	QtyA   float64  `json:"qtyA"`
-	QtyB   float64  `json:"qtyB"`
-	Delta  float64  `json:"delta"`
-	Method string   `json:"method"`           // sku | exact | fuzzy
+	QtyA   decimal.Decimal `json:"qtyA"`
+	QtyB   decimal.Decimal `json:"qtyB"`
+	Delta  decimal.Decimal `json:"delta"`     // QtyA-QtyB, точно
+	Status string   `json:"status"`           // match | within_tolerance | surplus (A>B) | shortage (A<B)
+	Method string   `json:"method"`           // crosswalk | sku | exact | fuzzy
 	Score  *float64 `json:"score,omitempty"`  // метрика схожести для fuzzy
+	Metric string   `json:"metric,omitempty"` // какая метрика дала score
+	SrcA   Source   `json:"sourceA"`          // где строка A в файле A
+	SrcB   Source   `json:"sourceB"`          // где строка B в файле B
+	PartsA []Part   `json:"partsA,omitempty"` // слитые дубли A
+	PartsB []Part   `json:"partsB,omitempty"` // слитые дубли B
+
+	Ambiguous    bool        `json:"ambiguous"`              // победитель выбран не по схожести (ничья в пределах eps)
+	Alternatives []Candidate `json:"alternatives,omitempty"` // почти равные кандидаты B
+	Candidates   []Candidate `json:"candidates,omitempty"`   // explain: рассмотренные кандидаты B
+}
+
+// Candidate — кандидат B, рассмотренный для строки A (режим explain)
+type Candidate struct {
+	IDB    int     `json:"idB"`
+	Name   string  `json:"name"`
+	Sku    string  `json:"sku"`
+	Qty    decimal.Decimal `json:"qty"`
+	Pass   string  `json:"pass"`             // проход, на котором кандидат допустим: crosswalk | sku | exact | fuzzy
+	Score  float64 `json:"score"`            // схожесть по метрике запроса
+	Chosen bool    `json:"chosen,omitempty"` // стал парой
+	Reason string  `json:"reason,omitempty"` // units_guard | below_threshold | already_used | strict_mode | outranked
+}
+
+
+// Suggestion — похожая несопоставленная строка другой таблицы для OnlyA/OnlyB
+type Suggestion struct {
+	ID    int     `json:"id"` // Row.ID в другой таблице
+	Name  string  `json:"name"`
+	Sku   string  `json:"sku"`
+	Qty   decimal.Decimal `json:"qty"`
+	Score float64         `json:"score"`
+}
+
+// UnmatchedRow — строка OnlyA / OnlyB
+type UnmatchedRow struct {
+	ID    int             `json:"id"` // Row.ID в своей таблице
+	Name  string          `json:"name"`
+	Key   string          `json:"key"` // нормализованное имя
+	Sku   string          `json:"sku"`
+	Qty   decimal.Decimal `json:"qty"`
+	Src   Source          `json:"source"`
+	Parts []Part          `json:"parts,omitempty"` // слитые дубли
+
+	Candidates  []Candidate  `json:"candidates,omitempty"`  // explain (только OnlyA)
+	Suggestions []Suggestion `json:"suggestions,omitempty"` // похожие несопоставленные строки другой таблицы
+}
+
+// Conflicts — подозрительные SKU и имена (пары не отменяются, только отчёт)
+type Conflicts struct {
+	SkuNameMismatch []SkuNameConflict `json:"skuNameMismatch"` // SKU совпал (или сведён таблицей), имена далеки
+	SkuManyNames    []KeyConflict     `json:"skuManyNames"`    // один SKU — разные имена в одной таблице
+	NameManySkus    []KeyConflict     `json:"nameManySkus"`    // одно имя — разные SKU в одной таблице
+}
+
+// SkuNameConflict — пара прохода sku или crosswalk с низкой схожестью имён
+type SkuNameConflict struct {
+	IDA   int     `json:"idA"`
+	IDB   int     `json:"idB"`
+	Sku   string  `json:"sku"`
+	NameA string  `json:"nameA"`
+	NameB string  `json:"nameB"`
+	Score float64 `json:"score"` // bestSimilarity нормализованных имён
+}
+
+// KeyConflict — SKU (или имя), под которым в одной таблице разные имена (или SKU)
+type KeyConflict struct {
+	Side     string    `json:"side"`          // A | B
+	Sku      string    `json:"sku,omitempty"` // для skuManyNames
+	Key      string    `json:"key,omitempty"` // для nameManySkus: нормализованное имя
+	Variants []Variant `json:"variants"`
+}
+
+// Variant — вариант имени (или SKU) внутри KeyConflict
+type Variant struct {
+	Sku  string `json:"sku,omitempty"`
+	Key  string `json:"key,omitempty"`
+	Name string `json:"name"`           // первое исходное наименование варианта
+	Rows []int  `json:"rows,omitempty"` // 1-based строки листа
+}
+
+// CrosswalkEntry — строка таблицы соответствия артикулов A → B
+type CrosswalkEntry struct {
+	Row   int    // 1-based строка файла соответствий (0 — неизвестна)
+	SkuA  string // артикул системы A как в таблице
+	SkuB  string // артикул системы B
+	NormA string // SkuA по правилам Mapping.Sku стороны A (пусто — TrimSpace(SkuA))
+	NormB string // SkuB по правилам стороны B
+}
+
+// CrosswalkReport — как сработала таблица соответствия артикулов
+type CrosswalkReport struct {
+	Entries       int                 `json:"entries"` // записей в таблице
+	Used          int                 `json:"used"`    // записей, давших пару crosswalk
+	Unused        []CrosswalkIssue    `json:"unused"`
+	Contradictory []CrosswalkConflict `json:"contradictory"`
+}
+
+// CrosswalkIssue — запись, не давшая пары
+type CrosswalkIssue struct {
+	Row    int    `json:"row,omitempty"`
+	SkuA   string `json:"skuA"`
+	SkuB   string `json:"skuB"`
+	Reason string `json:"reason"` // missing_a | missing_b | not_applied
+}
+
+// CrosswalkConflict — один артикул, сопоставленный нескольким артикулам другой системы
+type CrosswalkConflict struct {
+	Side    string   `json:"side"`    // A — артикул A ведёт в несколько B; B — наоборот
+	Sku     string   `json:"sku"`     // повторяющийся артикул
+	Targets []string `json:"targets"` // артикулы другой системы
+	Rows    []int    `json:"rows,omitempty"`
+}
+
+// PassStat — сколько пар сопоставлено на проходе каскада
+type PassStat struct {
+	Pass    string `json:"pass"`    // crosswalk | sku | exact | fuzzy
+	Matched int    `json:"matched"`
+}
+
+// Summary — итоги сверки для шапки отчёта (клиентам не нужно пересчитывать)
+type Summary struct {
+	InputA   int            `json:"inputA"`   // строк A до агрегации дублей
+	InputB   int            `json:"inputB"`   // строк B до агрегации
+	RowsA    int            `json:"rowsA"`    // строк A после агрегации
+	RowsB    int            `json:"rowsB"`    // строк B после агрегации
+	Matched  int            `json:"matched"`  // пар всего
+	ByMethod map[string]int `json:"byMethod"` // пары по проходу: crosswalk | sku | exact | fuzzy
+	ByStatus map[string]int `json:"byStatus"` // пары по статусу количества
+
+	OnlyA    int             `json:"onlyA"`    // несопоставленных строк A
+	OnlyB    int             `json:"onlyB"`    // несопоставленных строк B
+	OnlyAQty decimal.Decimal `json:"onlyAQty"` // сумма их количеств
+	OnlyBQty decimal.Decimal `json:"onlyBQty"`
+
+	AbsDelta decimal.Decimal `json:"absDelta"` // Σ|Δ| по парам
+	NetDelta decimal.Decimal `json:"netDelta"` // ΣΔ по парам (A − B)
+
+	Fuzzy ScoreStats `json:"fuzzy"` // распределение оценок fuzzy-пар
+}
+
+// ScoreStats — распределение оценок схожести (нули, если пар нет)
+type ScoreStats struct {
+	Count   int           `json:"count"`
+	Min     float64       `json:"min"`
+	Max     float64       `json:"max"`
+	Mean    float64       `json:"mean"`
+	Median  float64       `json:"median"`
+	Buckets []ScoreBucket `json:"buckets"` // от порога до 1 шагом 0.05
 }
 
+// ScoreBucket — интервал гистограммы [From, To); последний включает 1
+type ScoreBucket struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
 
 type Result struct {
-    Rows  []ResultRow      `json:"rows"`
-    OnlyA []map[string]any `json:"onlyA"`
-    OnlyB []map[string]any `json:"onlyB"`
-    Opts  Options          `json:"opts"`
-    MapA  Mapping          `json:"mapA"`
-    MapB  Mapping          `json:"mapB"`
+    Rows   []ResultRow      `json:"rows"`
+    OnlyA  []UnmatchedRow   `json:"onlyA"`
+    OnlyB  []UnmatchedRow   `json:"onlyB"`
+    Passes []PassStat       `json:"passes"`
+    UsedB  []int            `json:"usedB"` // Row.ID строк B, вошедших в пары
+    Duplicates []DuplicateGroup `json:"duplicates"` // что слил aggregate (A, затем B)
+    Conflicts  Conflicts        `json:"conflicts"`
+    Crosswalk  CrosswalkReport  `json:"crosswalk"`
+    Summary Summary         `json:"summary"`
+
+    DictionaryVersion int `json:"dictionaryVersion"` // версия словаря, с которой считали
+    Opts   Options          `json:"opts"`
+    MapA   Mapping          `json:"mapA"`
+    MapB   Mapping          `json:"mapB"`
 }
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"strings"
+
+	"recon-service/internal/reconcile/model"
+)
+
+// --------- АГРЕГАЦИЯ ДУБЛЕЙ (opt.Aggregate) ---------
+
+// Режимы агрегации (значения opt.Aggregate)
+const (
+	AggregateAuto    = "auto"     // по SKU, а строки без SKU — по имени (прежнее поведение)
+	AggregateNone    = "none"     // не сливать
+	AggregateSku     = "sku"      // только по SKU; строки без SKU не сливаются
+	AggregateName    = "name"     // по нормализованному имени, SKU не важен
+	AggregateSkuName = "sku+name" // совпадают и SKU, и имя
+)
+
+// Aggregations — поддерживаемые режимы (для валидации и подсказок)
+func Aggregations() []string {
+	return []string{AggregateAuto, AggregateNone, AggregateSku, AggregateName, AggregateSkuName}
+}
+
+// ValidAggregation — известен ли режим; пустое имя — режим по умолчанию
+func ValidAggregation(mode string) bool {
+	if mode == "" {
+		return true
+	}
+	for _, m := range Aggregations() {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// aggKey — ключ слияния строки и его вид; пустой ключ — строка не сливается
+func aggKey(r model.Row, mode string) (key, by string) {
+	sku := r.SkuNorm
+	name := strings.TrimSpace(r.NameNorm)
+	switch mode {
+	case AggregateNone:
+		return "", ""
+	case AggregateSku:
+		return sku, AggregateSku
+	case AggregateName:
+		return name, AggregateName
+	case AggregateSkuName:
+		if name == "" {
+			return "", ""
+		}
+		return sku + "\x00" + name, AggregateSkuName
+	default:
+		if sku != "" {
+			return "sku\x00" + sku, AggregateSku
+		}
+		if name == "" {
+			return "", ""
+		}
+		return "name\x00" + name, AggregateName
+	}
+}
+
+// aggregate сливает дубли по ключу режима opt.Aggregate, суммируя количества.
+// Порядок первых вхождений сохраняется — результат сверки детерминирован,
+// а Row.ID итоговых строк равен их позиции. Вторым значением — группы
+// слитых строк (side — "A" или "B") для отчёта Result.Duplicates.
+func aggregate(rows []model.Row, opt model.Options, side string) ([]model.Row, []model.DuplicateGroup) {
+	pos := make(map[string]int)
+	by := make(map[int]string) // позиция агрегата → вид ключа
+	out := make([]model.Row, 0, len(rows))
+	for _, r := range rows {
+		if r.NameNorm == "" {
+			r.NameNorm = normalize(r.Name, opt)
+		}
+		key, kind := aggKey(r, opt.Aggregate)
+		if i, ok := pos[key]; ok && key != "" {
+			if out[i].Parts == nil {
+				out[i].Parts = []model.Part{partOf(out[i])}
+			}
+			out[i].Parts = append(out[i].Parts, partOf(r))
+			out[i].Qty = out[i].Qty.Add(r.Qty) // точная сумма, без остатков float
+			out[i].Src = mergeSource(out[i].Src, r.Src)
+			continue
+		}
+		if key != "" {
+			pos[key] = len(out)
+			by[len(out)] = kind
+		}
+		r.ID = len(out)
+		r.Parts = nil
+		out = append(out, r)
+	}
+
+	dups := make([]model.DuplicateGroup, 0)
+	for i, r := range out {
+		if r.Parts == nil {
+			continue
+		}
+		dups = append(dups, model.DuplicateGroup{
+			Side:  side,
+			ID:    r.ID,
+			By:    by[i],
+			Sku:   r.Sku,
+			Key:   r.NameNorm,
+			Qty:   r.Qty,
+			Parts: r.Parts,
+		})
+	}
+	return out, dups
+}
+
+// partOf — строка как часть агрегата (вызывается до слияния, пока у строки
+// одна исходная строка листа)
+func partOf(r model.Row) model.Part {
+	p := model.Part{Name: r.Name, Sku: r.Sku, Qty: r.Qty}
+	if len(r.Src.Rows) > 0 {
+		p.Row = r.Src.Rows[0]
+	}
+	return p
+}
+
+// mergeSource дописывает исходные строки дубля к строке-агрегату.
+// Срезы копируем: у первого вхождения они могут быть общими с входом.
+func mergeSource(dst, src model.Source) model.Source {
+	dst.Rows = append(dst.Rows[:len(dst.Rows):len(dst.Rows)], src.Rows...)
+	dst.Cells = append(dst.Cells[:len(dst.Cells):len(dst.Cells)], src.Cells...)
+	return dst
+}
@@ -0,0 +1,434 @@
+package service
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+	"strings"
+
+	"recon-service/internal/decimal"
+	"recon-service/internal/reconcile/model"
+)
+
+// --------- ГЛОБАЛЬНОЕ НАЗНАЧЕНИЕ A↔B (assignment=optimal) ---------
+//
+// В каждом проходе каскада сначала считаем все допустимые пары, затем
+// решаем задачу о назначениях на графе кандидатов: максимизируем число
+// пар (каждое ребро, прошедшее фильтр прохода, должно иметь шанс стать
+// парой — в том числе со схожестью 0), среди них — суммарную схожесть,
+// при равенстве — минимизируем суммарную |Δ|. Результат не зависит ни от
+// числа CPU, ни от порядка обработки строк.
+
+// edge — допустимая пара (строка A, строка B) с оценкой
+type edge struct {
+	a, b   int
+	sim    float64
+	delta  decimal.Decimal // |Δ| количеств, точно
+	dq     int64           // delta в целых единицах общей точности (scaleDeltas)
+	method string
+}
+
+// passEdges собирает допустимые пары одного прохода каскада: только
+// строки A, ещё не сопоставленные, и строки B, ещё не использованные.
+func passEdges(pass string, a, b []model.Row, idxB *Index, sim Similarity, ranked [][]scoredName, matches []match, usedB []bool) []edge {
+	var edges []edge
+	for i, ar := range a {
+		if matches[i].b >= 0 {
+			continue
+		}
+		seen := make(map[int]bool)
+		add := func(j int, sim float64) {
+			if usedB[j] || seen[j] {
+				return
+			}
+			seen[j] = true
+			edges = append(edges, edge{
+				a:      i,
+				b:      j,
+				sim:    sim,
+				delta:  ar.Qty.Sub(b[j].Qty).Abs(),
+				method: pass,
+			})
+		}
+
+		switch pass {
+		case passCrosswalk:
+			for _, j := range idxB.crosswalkTargets(ar.SkuNorm) {
+				add(j, sim.Score(ar.NameNorm, b[j].NameNorm))
+			}
+		case passSku:
+			if s := ar.SkuNorm; s != "" {
+				for _, j := range idxB.bySku[s] {
+					add(j, sim.Score(ar.NameNorm, b[j].NameNorm))
+				}
+			}
+		case passExact:
+			if strings.TrimSpace(ar.NameNorm) != "" {
+				for _, j := range idxB.byName[ar.NameNorm] {
+					add(j, 1)
+				}
+			}
+		case passFuzzy:
+			for _, c := range ranked[i] {
+				for _, j := range idxB.byName[c.name] {
+					add(j, c.score)
+				}
+			}
+		}
+	}
+	return edges
+}
+
+// runOptimal — сверка в режиме assignment=optimal (a и b уже нормализованы
+// и агрегированы). Каскад проходов тот же, что и в жадном режиме, но
+// внутри каждого прохода назначение решается глобально.
+func runOptimal(a, b []model.Row, idxB *Index, opt model.Options) model.Result {
+	matches := newMatches(len(a))
+	usedB := make([]bool, len(b))
+	counts := make(map[string]int, len(passOrder))
+	sim := newScoreCache(newSimilarity(opt, idxB)) // оценки пар переиспользуются между проходами
+	eps := tieEpsilon(opt)
+
+	for _, pass := range passOrder {
+		var ranked [][]scoredName
+		if pass == passFuzzy {
+			if !opt.EnableFuzzy || opt.StrictAfterNorm {
+				continue
+			}
+			pending := make([]bool, len(a))
+			for i := range a {
+				pending[i] = matches[i].b < 0
+			}
+			ranked = newFuzzyScorer(idxB, opt, sim).rankAll(a, pending)
+		}
+
+		edges := passEdges(pass, a, b, idxB, sim, ranked, matches, usedB)
+		byA := make(map[int][]int) // строка A -> её рёбра (для альтернатив)
+		for k, e := range edges {
+			byA[e.a] = append(byA[e.a], k)
+		}
+		mb, me := solveAssignment(len(a), len(b), edges)
+		for i, j := range mb {
+			if j < 0 {
+				continue
+			}
+			matches[i] = match{b: j, method: pass}
+			// альтернативы — рёбра той же строки A в пределах eps от выбранного;
+			// занятые другими строками A уберёт pruneAlts
+			for _, k := range byA[i] {
+				if e := edges[k]; e.b != j && math.Abs(e.sim-edges[me[i]].sim) <= eps {
+					matches[i].alts = append(matches[i].alts, alt{b: e.b, sim: e.sim})
+				}
+			}
+			if pass == passFuzzy {
+				s := edges[me[i]].sim
+				matches[i].score = &s
+				matches[i].metric = sim.Name()
+			}
+			usedB[j] = true
+			counts[pass]++
+		}
+	}
+
+	pruneAlts(matches, usedB)
+	return assemble(a, b, opt, matches, usedB, counts, buildDetails(a, b, idxB, sim, opt, matches, usedB))
+}
+
+// solveAssignment возвращает для каждой строки A индекс выбранной строки B
+// (или -1) и номер использованного ребра. Граф кандидатов разбивается на
+// связные компоненты, каждая решается независимо.
+func solveAssignment(nA, nB int, edges []edge) (matchB []int, matchEdge []int) {
+	matchB = make([]int, nA)
+	matchEdge = make([]int, nA)
+	for i := range matchB {
+		matchB[i] = -1
+		matchEdge[i] = -1
+	}
+	if len(edges) == 0 {
+		return matchB, matchEdge
+	}
+	scaleDeltas(edges)
+
+	// union-find: вершины A — [0,nA), вершины B — [nA,nA+nB)
+	parent := make([]int, nA+nB)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(x int) int {
+		for parent[x] != x {
+			parent[x] = parent[parent[x]]
+			x = parent[x]
+		}
+		return x
+	}
+	for _, e := range edges {
+		ra, rb := find(e.a), find(nA+e.b)
+		if ra != rb {
+			if ra < rb {
+				parent[rb] = ra
+			} else {
+				parent[ra] = rb
+			}
+		}
+	}
+
+	comps := make(map[int][]int) // root -> номера рёбер
+	roots := make([]int, 0)
+	for k, e := range edges {
+		r := find(e.a)
+		if _, ok := comps[r]; !ok {
+			roots = append(roots, r)
+		}
+		comps[r] = append(comps[r], k)
+	}
+	sort.Ints(roots)
+
+	for _, r := range roots {
+		solveComponent(edges, comps[r], matchB, matchEdge)
+	}
+	return matchB, matchEdge
+}
+
+// scaleDeltas переводит |Δ| рёбер в целые единицы самой мелкой точности
+// среди них: тай-брейк по |Δ| различает любые знаки, сколько бы их ни
+// оставил QtyPrecision. Стоимости путей и потенциалы не превышают суммы
+// всех |Δ|, поэтому точность понижается (с округлением), только если эта
+// сумма не помещается в int64 с запасом.
+func scaleDeltas(edges []edge) {
+	const limit = math.MaxInt64 / 4
+	var scale int32
+	for _, e := range edges {
+		scale = max(scale, e.delta.Scale())
+	}
+	for ; ; scale-- {
+		var total int64
+		fits := true
+		for k := range edges {
+			v, ok := edges[k].delta.Unscaled(scale)
+			if !ok || v > limit-total {
+				v, fits = limit/int64(len(edges)), false
+			}
+			edges[k].dq = v
+			total += v
+		}
+		if fits || scale <= 0 {
+			return
+		}
+	}
+}
+
+// lexCost — лексикографическая стоимость: сначала -число пар, затем
+// -схожесть, затем |Δ|. Все компоненты целочисленные, чтобы сравнения
+// были точными.
+type lexCost struct{ n, p, s int64 }
+
+func (c lexCost) add(o lexCost) lexCost { return lexCost{c.n + o.n, c.p + o.p, c.s + o.s} }
+func (c lexCost) sub(o lexCost) lexCost { return lexCost{c.n - o.n, c.p - o.p, c.s - o.s} }
+func (c lexCost) less(o lexCost) bool {
+	if c.n != o.n {
+		return c.n < o.n
+	}
+	return c.p < o.p || (c.p == o.p && c.s < o.s)
+}
+func maxLex(x, y lexCost) lexCost {
+	if x.less(y) {
+		return y
+	}
+	return x
+}
+func edgeCost(e edge) lexCost {
+	return lexCost{
+		n: -1,
+		p: -int64(math.Round(e.sim * 1e6)),
+		s: e.dq,
+	}
+}
+
+type flowArc struct {
+	to, rev int
+	cap     int
+	cost    lexCost
+	edge    int // номер исходного ребра (для A→B), иначе -1
+}
+
+// solveComponent — min-cost flow (последовательные кратчайшие пути с
+// потенциалами) на одной компоненте. Каждый увеличивающий путь добавляет
+// ровно одну пару (компонента n пути всегда -1), поэтому поток растим до
+// максимального паросочетания, а кратчайшие пути дают среди них лучшее по
+// схожести и |Δ|. Отсекать пути по знаку стоимости нельзя: пара SKU со
+// схожестью имён 0 иначе никогда не будет выбрана.
+func solveComponent(edges []edge, ids []int, matchB, matchEdge []int) {
+	// локальная нумерация вершин в стабильном порядке
+	aLoc := make(map[int]int)
+	bLoc := make(map[int]int)
+	var aList, bList []int
+	for _, k := range ids {
+		e := edges[k]
+		if _, ok := aLoc[e.a]; !ok {
+			aLoc[e.a] = -1
+			aList = append(aList, e.a)
+		}
+		if _, ok := bLoc[e.b]; !ok {
+			bLoc[e.b] = -1
+			bList = append(bList, e.b)
+		}
+	}
+	sort.Ints(aList)
+	sort.Ints(bList)
+	for i, v := range aList {
+		aLoc[v] = 1 + i
+	}
+	for i, v := range bList {
+		bLoc[v] = 1 + len(aList) + i
+	}
+	src, sink := 0, 1+len(aList)+len(bList)
+	n := sink + 1
+
+	g := make([][]flowArc, n)
+	addArc := func(u, v int, cost lexCost, ek int) {
+		g[u] = append(g[u], flowArc{to: v, rev: len(g[v]), cap: 1, cost: cost, edge: ek})
+		g[v] = append(g[v], flowArc{to: u, rev: len(g[u]) - 1, cap: 0, cost: lexCost{}.sub(cost), edge: -1})
+	}
+	for _, v := range aList {
+		addArc(src, aLoc[v], lexCost{}, -1)
+	}
+	sorted := append([]int(nil), ids...)
+	sort.Slice(sorted, func(x, y int) bool {
+		ex, ey := edges[sorted[x]], edges[sorted[y]]
+		if ex.a != ey.a {
+			return ex.a < ey.a
+		}
+		return ex.b < ey.b
+	})
+	for _, k := range sorted {
+		e := edges[k]
+		addArc(aLoc[e.a], bLoc[e.b], edgeCost(e), k)
+	}
+	for _, v := range bList {
+		addArc(bLoc[v], sink, lexCost{}, -1)
+	}
+
+	// начальные потенциалы: граф ацикличен (S→A→B→T), считаем напрямую
+	pot := make([]lexCost, n)
+	potSet := make([]bool, n)
+	potSet[src] = true
+	for _, v := range aList {
+		potSet[aLoc[v]] = true
+	}
+	for _, v := range aList {
+		u := aLoc[v]
+		for _, arc := range g[u] {
+			if arc.cap == 0 || arc.to == src {
+				continue
+			}
+			if !potSet[arc.to] || arc.cost.less(pot[arc.to]) {
+				pot[arc.to] = arc.cost
+				potSet[arc.to] = true
+			}
+		}
+	}
+	for _, v := range bList {
+		b := bLoc[v]
+		if !potSet[sink] || pot[b].less(pot[sink]) {
+			pot[sink] = pot[b]
+			potSet[sink] = true
+		}
+	}
+
+	dist := make([]lexCost, n)
+	reached := make([]bool, n)
+	prevNode := make([]int, n)
+	prevArc := make([]int, n)
+
+	for {
+		for i := range reached {
+			reached[i] = false
+		}
+		dist[src] = lexCost{}
+		reached[src] = true
+		pq := &lexHeap{{node: src}}
+		done := make([]bool, n)
+		for pq.Len() > 0 {
+			it := heap.Pop(pq).(lexItem)
+			u := it.node
+			if done[u] {
+				continue
+			}
+			done[u] = true
+			for ai, arc := range g[u] {
+				if arc.cap == 0 {
+					continue
+				}
+				rc := arc.cost.add(pot[u]).sub(pot[arc.to])
+				nd := dist[u].add(rc)
+				if !reached[arc.to] || nd.less(dist[arc.to]) {
+					reached[arc.to] = true
+					dist[arc.to] = nd
+					prevNode[arc.to] = u
+					prevArc[arc.to] = ai
+					heap.Push(pq, lexItem{cost: nd, node: arc.to})
+				}
+			}
+		}
+		if !reached[sink] {
+			break
+		}
+
+		maxD := lexCost{}
+		for v := 0; v < n; v++ {
+			if reached[v] {
+				maxD = maxLex(maxD, dist[v])
+			}
+		}
+		for v := 0; v < n; v++ {
+			if reached[v] {
+				pot[v] = pot[v].add(dist[v])
+			} else {
+				pot[v] = pot[v].add(maxD)
+			}
+		}
+
+		for v := sink; v != src; v = prevNode[v] {
+			u := prevNode[v]
+			arc := &g[u][prevArc[v]]
+			arc.cap--
+			g[v][arc.rev].cap++
+		}
+	}
+
+	// считываем назначение из насыщенных дуг A→B
+	for _, v := range aList {
+		for _, arc := range g[aLoc[v]] {
+			if arc.edge >= 0 && arc.cap == 0 {
+				e := edges[arc.edge]
+				matchB[e.a] = e.b
+				matchEdge[e.a] = arc.edge
+			}
+		}
+	}
+}
+
+type lexItem struct {
+	cost lexCost
+	node int
+}
+
+// lexHeap — min-heap по стоимости; при равенстве — по номеру вершины (детерминизм)
+type lexHeap []lexItem
+
+func (h lexHeap) Len() int { return len(h) }
+func (h lexHeap) Less(i, j int) bool {
+	if h[i].cost == h[j].cost {
+		return h[i].node < h[j].node
+	}
+	return h[i].cost.less(h[j].cost)
+}
+func (h lexHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *lexHeap) Push(x any)   { *h = append(*h, x.(lexItem)) }
+func (h *lexHeap) Pop() any {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"math/rand"
+	"testing"
+
+	"recon-service/internal/decimal"
+	"recon-service/internal/reconcile/model"
+)
+
+// bruteAssignment — лучшая стоимость назначения полным перебором
+// (для малых графов): тот же лексикографический порядок, что у solveAssignment.
+func bruteAssignment(nA, nB int, edges []edge) lexCost {
+	byA := make([][]int, nA)
+	for k, e := range edges {
+		byA[e.a] = append(byA[e.a], k)
+	}
+	usedB := make([]bool, nB)
+	var best lexCost
+	var rec func(i int, cur lexCost)
+	rec = func(i int, cur lexCost) {
+		if i == nA {
+			if cur.less(best) {
+				best = cur
+			}
+			return
+		}
+		rec(i+1, cur) // строка A без пары
+		for _, k := range byA[i] {
+			e := edges[k]
+			if usedB[e.b] {
+				continue
+			}
+			usedB[e.b] = true
+			rec(i+1, cur.add(edgeCost(e)))
+			usedB[e.b] = false
+		}
+	}
+	rec(0, lexCost{})
+	return best
+}
+
+func TestSolveAssignmentMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	sims := []float64{0, 0.25, 0.5, 0.83, 0.9, 1}
+	for iter := 0; iter < 3000; iter++ {
+		nA, nB := 1+rng.Intn(5), 1+rng.Intn(5)
+		var edges []edge
+		for i := 0; i < nA; i++ {
+			for j := 0; j < nB; j++ {
+				if rng.Intn(2) == 0 {
+					continue
+				}
+				edges = append(edges, edge{
+					a:     i,
+					b:     j,
+					sim:   sims[rng.Intn(len(sims))],
+					delta: decimal.New(int64(rng.Intn(3)), 4), // мельче 0.001
+				})
+			}
+		}
+
+		mb, me := solveAssignment(nA, nB, edges)
+		var got lexCost
+		usedB := make([]bool, nB)
+		for i, j := range mb {
+			if j < 0 {
+				continue
+			}
+			e := edges[me[i]]
+			if e.a != i || e.b != j {
+				t.Fatalf("iter %d: строка %d: ребро %d не соответствует паре %d", iter, i, me[i], j)
+			}
+			if usedB[j] {
+				t.Fatalf("iter %d: строка B %d назначена дважды", iter, j)
+			}
+			usedB[j] = true
+			got = got.add(edgeCost(e))
+		}
+		if want := bruteAssignment(nA, nB, edges); got != want {
+			t.Fatalf("iter %d: стоимость %+v, перебор %+v (edges %+v)", iter, got, want, edges)
+		}
+	}
+}
+
+// Пара прохода sku/crosswalk со схожестью имён 0 должна сопоставляться
+// и в optimal, как в greedy.
+func TestOptimalKeepsZeroSimilarityPairs(t *testing.T) {
+	rows := func(names ...string) []model.Row {
+		out := make([]model.Row, len(names))
+		for i, n := range names {
+			out[i] = model.Row{Name: n, Sku: "S" + string(rune('1'+i)), Qty: decimal.New(1, 0)}
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		name   string
+		metric string
+		a, b   []string
+		cw     []model.CrosswalkEntry
+	}{
+		{name: "sku/damerau", metric: MetricDamerau, a: []string{"Болт", "Шайба"}, b: []string{"Гайка", "Винт"}},
+		{
+			name: "crosswalk/jaro_winkler", metric: MetricJaroWinkler,
+			a: []string{"abc", "def"}, b: []string{"xyz", "uvw"},
+			cw: []model.CrosswalkEntry{{SkuA: "S1", SkuB: "S2"}, {SkuA: "S2", SkuB: "S1"}},
+		},
+	} {
+		for _, mode := range []string{"greedy", "optimal"} {
+			opt := model.Options{
+				Normalization: true,
+				Lowercase:     true,
+				Threshold:     0.83,
+				QtyPrecision:  -1,
+				Metric:        tc.metric,
+				Assignment:    mode,
+				Crosswalk:     tc.cw,
+			}
+			res := Run(rows(tc.a...), rows(tc.b...), opt)
+			if len(res.Rows) != 2 || len(res.OnlyA) != 0 {
+				t.Errorf("%s/%s: пар %d, OnlyA %d; ждём 2 и 0", tc.name, mode, len(res.Rows), len(res.OnlyA))
+			}
+		}
+	}
+}
+
+// Строки B, которые проход отдал другим строкам A, не попадают в
+// альтернативы — ни в optimal, ни в жадном режиме (exact и fuzzy).
+func TestAlternativesSkipTakenRows(t *testing.T) {
+	rows := func(names ...string) []model.Row {
+		out := make([]model.Row, len(names))
+		for i, n := range names {
+			out[i] = model.Row{Name: n, Qty: decimal.New(1, 0)}
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		name string
+		a, b []string
+	}{
+		{name: "exact", a: []string{"Болт М10", "Болт М10"}, b: []string{"Болт М10", "Болт М10", "Гайка М10"}},
+		{
+			name: "fuzzy",
+			a:    []string{"Болт М10 оцинкованный", "Болт М10 оцинкованный"},
+			b:    []string{"Болт М10 оцинкованныйй", "Болт М10 оцинкованныйй", "Гайка М10"},
+		},
+	} {
+		for _, mode := range []string{"greedy", "optimal"} {
+			opt := model.Options{
+				Normalization: true,
+				Lowercase:     true,
+				EnableFuzzy:   true,
+				Threshold:     0.83,
+				QtyPrecision:  -1,
+				Assignment:    mode,
+				Aggregate:     AggregateNone, // дубли не сливаем: две одинаковые строки A
+			}
+			res := Run(rows(tc.a...), rows(tc.b...), opt)
+			if len(res.Rows) != 2 {
+				t.Fatalf("%s/%s: пар %d, ждём 2", tc.name, mode, len(res.Rows))
+			}
+			for _, r := range res.Rows {
+				if r.Ambiguous || len(r.Alternatives) != 0 {
+					t.Errorf("%s/%s: строка A %d: альтернативы %v, ждём пусто", tc.name, mode, r.IDA, r.Alternatives)
+				}
+			}
+		}
+	}
+}
+
+// Тай-брейк по |Δ| точный: при равной схожести берём строку B с меньшей
+// дельтой, даже если дельты различаются лишь в четвёртом знаке.
+func TestOptimalTieBreakUsesExactDelta(t *testing.T) {
+	row := func(q string) model.Row {
+		return model.Row{Name: "Болт М10", Qty: decimal.MustParse(q)}
+	}
+	opt := model.Options{
+		Normalization: true,
+		Lowercase:     true,
+		Threshold:     0.83,
+		QtyPrecision:  -1,
+		Assignment:    "optimal",
+		Aggregate:     AggregateNone,
+	}
+	res := Run([]model.Row{row("1")}, []model.Row{row("1.0004"), row("1.0001")}, opt)
+	if len(res.Rows) != 1 || res.Rows[0].QtyB.String() != "1.0001" {
+		t.Fatalf("пары %+v, ждём пару с QtyB 1.0001", res.Rows)
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"strings"
+
+	"recon-service/internal/reconcile/model"
+)
+
+// --------- КОНФЛИКТЫ SKU / ИМЁН ---------
+
+// defaultSkuNameFloor — порог схожести имён SKU-пары, если opt.SkuNameFloor не задан
+const defaultSkuNameFloor = 0.5
+
+// skuNameMismatches — пары проходов sku и crosswalk, у которых bestSimilarity
+// имён ниже порога: опечатка в артикуле одной из систем (или в таблице
+// соответствия) склеивает разные товары, а эти проходы имена не сравнивают.
+func skuNameMismatches(a, b []model.Row, matches []match, opt model.Options) []model.SkuNameConflict {
+	floor := opt.SkuNameFloor
+	if floor == 0 {
+		floor = defaultSkuNameFloor
+	}
+	out := make([]model.SkuNameConflict, 0)
+	if floor < 0 {
+		return out
+	}
+	for i, m := range matches {
+		if m.b < 0 || (m.method != passSku && m.method != passCrosswalk) {
+			continue
+		}
+		ar, br := a[i], b[m.b]
+		if s := bestSimilarity(ar.NameNorm, br.NameNorm); s < floor {
+			out = append(out, model.SkuNameConflict{
+				IDA:   ar.ID,
+				IDB:   br.ID,
+				Sku:   ar.Sku,
+				NameA: ar.Name,
+				NameB: br.Name,
+				Score: s,
+			})
+		}
+	}
+	return out
+}
+
+// keyConflicts ищет внутри одной таблицы (до агрегации, иначе варианты уже
+// слиты) SKU с несколькими NameNorm и NameNorm с несколькими SKU.
+// Порядок — по первому вхождению, как в aggregate.
+func keyConflicts(rows []model.Row, side string) (skuNames, nameSkus []model.KeyConflict) {
+	type group struct {
+		key      string
+		variants []model.Variant
+		pos      map[string]int // вариант → индекс в variants
+	}
+	add := func(groups map[string]*group, order *[]string, key, variant string, v model.Variant, src []int) {
+		g, ok := groups[key]
+		if !ok {
+			g = &group{key: key, pos: make(map[string]int)}
+			groups[key] = g
+			*order = append(*order, key)
+		}
+		i, ok := g.pos[variant]
+		if !ok {
+			i = len(g.variants)
+			g.pos[variant] = i
+			g.variants = append(g.variants, v)
+		}
+		g.variants[i].Rows = append(g.variants[i].Rows, src...)
+	}
+
+	bySku, byName := make(map[string]*group), make(map[string]*group)
+	var skuOrder, nameOrder []string
+	for _, r := range rows {
+		sku := r.SkuNorm
+		name := strings.TrimSpace(r.NameNorm)
+		if sku == "" || name == "" {
+			continue
+		}
+		add(bySku, &skuOrder, sku, name, model.Variant{Key: name, Name: r.Name}, r.Src.Rows)
+		add(byName, &nameOrder, name, sku, model.Variant{Sku: sku, Name: r.Name}, r.Src.Rows)
+	}
+
+	skuNames, nameSkus = make([]model.KeyConflict, 0), make([]model.KeyConflict, 0)
+	for _, k := range skuOrder {
+		if g := bySku[k]; len(g.variants) > 1 {
+			skuNames = append(skuNames, model.KeyConflict{Side: side, Sku: k, Variants: g.variants})
+		}
+	}
+	for _, k := range nameOrder {
+		if g := byName[k]; len(g.variants) > 1 {
+			nameSkus = append(nameSkus, model.KeyConflict{Side: side, Key: k, Variants: g.variants})
+		}
+	}
+	return skuNames, nameSkus
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"slices"
+	"strings"
+
+	"recon-service/internal/reconcile/model"
+)
+
+// --------- ТАБЛИЦА СООТВЕТСТВИЯ АРТИКУЛОВ A → B (opt.Crosswalk) ---------
+
+// Причины, по которым запись таблицы соответствия не дала пары
+const (
+	crosswalkMissingA   = "missing_a"   // артикула A нет в файле A
+	crosswalkMissingB   = "missing_b"   // артикула B нет в файле B
+	crosswalkNotApplied = "not_applied" // обе строки есть, но пара собрана иначе (строки заняты или выбран другой B)
+)
+
+// crosswalk — нормализованные пары артикулов: A → различные B в порядке записей
+type crosswalk map[string][]string
+
+// newCrosswalk собирает таблицу из opt.Crosswalk; пустые артикулы пропускаются
+func newCrosswalk(entries []model.CrosswalkEntry) crosswalk {
+	if len(entries) == 0 {
+		return nil
+	}
+	cw := make(crosswalk)
+	for _, e := range entries {
+		na, nb := crosswalkNorm(e)
+		if na == "" || nb == "" {
+			continue
+		}
+		if !slices.Contains(cw[na], nb) {
+			cw[na] = append(cw[na], nb)
+		}
+	}
+	return cw
+}
+
+// crosswalkNorm — нормализованные артикулы записи (как Row.SkuNorm в Run)
+func crosswalkNorm(e model.CrosswalkEntry) (string, string) {
+	na, nb := e.NormA, e.NormB
+	if na == "" {
+		na = strings.TrimSpace(e.SkuA)
+	}
+	if nb == "" {
+		nb = strings.TrimSpace(e.SkuB)
+	}
+	return na, nb
+}
+
+// crosswalkTargets — строки B, в которые таблица переводит артикул A (по Row.ID)
+func (idx *Index) crosswalkTargets(skuA string) []int {
+	if skuA == "" {
+		return nil
+	}
+	var out []int
+	for _, sb := range idx.cw[skuA] {
+		out = append(out, idx.bySku[sb]...)
+	}
+	return out
+}
+
+// crosswalkReport — сколько записей дали пары, какие нет (и почему) и какие
+// противоречат друг другу. a и b — после агрегации, rows — итоговые пары.
+func crosswalkReport(entries []model.CrosswalkEntry, a, b []model.Row, rows []model.ResultRow) model.CrosswalkReport {
+	rep := model.CrosswalkReport{
+		Entries:       len(entries),
+		Unused:        make([]model.CrosswalkIssue, 0),
+		Contradictory: make([]model.CrosswalkConflict, 0),
+	}
+	if len(entries) == 0 {
+		return rep
+	}
+
+	inA := make(map[string]bool, len(a))
+	for _, r := range a {
+		inA[r.SkuNorm] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, r := range b {
+		inB[r.SkuNorm] = true
+	}
+	used := make(map[[2]string]bool)
+	for _, r := range rows {
+		if r.Method == passCrosswalk {
+			used[[2]string{a[r.IDA].SkuNorm, b[r.IDB].SkuNorm}] = true
+		}
+	}
+
+	for _, e := range entries {
+		na, nb := crosswalkNorm(e)
+		reason := ""
+		switch {
+		case used[[2]string{na, nb}]:
+			rep.Used++
+			continue
+		case na == "" || !inA[na]:
+			reason = crosswalkMissingA
+		case nb == "" || !inB[nb]:
+			reason = crosswalkMissingB
+		default:
+			reason = crosswalkNotApplied
+		}
+		rep.Unused = append(rep.Unused, model.CrosswalkIssue{
+			Row:    e.Row,
+			SkuA:   e.SkuA,
+			SkuB:   e.SkuB,
+			Reason: reason,
+		})
+	}
+
+	rep.Contradictory = append(rep.Contradictory, crosswalkConflicts(entries, "A")...)
+	rep.Contradictory = append(rep.Contradictory, crosswalkConflicts(entries, "B")...)
+	return rep
+}
+
+// crosswalkConflicts — артикулы стороны side, которым таблица сопоставила
+// несколько различных артикулов другой стороны (порядок — по первой записи)
+func crosswalkConflicts(entries []model.CrosswalkEntry, side string) []model.CrosswalkConflict {
+	type group struct {
+		sku     string // как в первой записи
+		targets []string
+		norms   []string
+		rows    []int
+	}
+	groups := make(map[string]*group)
+	var order []string
+	for _, e := range entries {
+		na, nb := crosswalkNorm(e)
+		key, target, raw := na, nb, e.SkuA
+		rawTarget := e.SkuB
+		if side == "B" {
+			key, target, raw, rawTarget = nb, na, e.SkuB, e.SkuA
+		}
+		if key == "" || target == "" {
+			continue
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &group{sku: raw}
+			groups[key] = g
+			order = append(order, key)
+		}
+		if !slices.Contains(g.norms, target) {
+			g.norms = append(g.norms, target)
+			g.targets = append(g.targets, rawTarget)
+		}
+		if e.Row > 0 {
+			g.rows = append(g.rows, e.Row)
+		}
+	}
+
+	var out []model.CrosswalkConflict
+	for _, k := range order {
+		if g := groups[k]; len(g.norms) > 1 {
+			out = append(out, model.CrosswalkConflict{Side: side, Sku: g.sku, Targets: g.targets, Rows: g.rows})
+		}
+	}
+	return out
+}
@@ -1,39 +1,110 @@
 package service
 
+import "sync"
+
+// editBuf — переиспользуемые буферы одного сравнения: руны обеих строк и
+// три строки DP (третья нужна для транспозиций). Берутся из пула, так что
+// в установившемся режиме сравнение не аллоцирует.
+type editBuf struct {
+	ra, rb           []rune
+	prev2, prev, cur []int
+}
+
+var editPool = sync.Pool{New: func() any { return new(editBuf) }}
+
+func appendRunes(dst []rune, s string) []rune {
+	for _, r := range s {
+		dst = append(dst, r)
+	}
+	return dst
+}
+
+func growInts(b []int, n int) []int {
+	if cap(b) < n {
+		return make([]int, n)
+	}
+	return b[:n]
+}
+
+// damerauLevenshtein — расстояние Дамерау–Левенштейна (вариант OSA:
+// вставка, удаление, замена, перестановка соседних символов).
 func damerauLevenshtein(a, b string) int {
-	ra := []rune(a)
-	rb := []rune(b)
-	al, bl := len(ra), len(rb)
+	return damerauBounded(a, b, -1)
+}
 
-	dp := make([][]int, al+1)
-	for i := 0; i <= al; i++ {
-		dp[i] = make([]int, bl+1)
+// damerauBounded считает расстояние, но бросает счёт, как только оно
+// заведомо превысит limit (минимум строки DP не убывает). В этом случае
+// возвращает limit+1. limit < 0 — без ограничения.
+func damerauBounded(a, b string, limit int) int {
+	if a == b {
+		return 0
 	}
-	for i := 0; i <= al; i++ {
-		dp[i][0] = i
+	buf := editPool.Get().(*editBuf)
+	defer editPool.Put(buf)
+
+	buf.ra = appendRunes(buf.ra[:0], a)
+	buf.rb = appendRunes(buf.rb[:0], b)
+	ra, rb := buf.ra, buf.rb
+
+	// общие префикс и суффикс на расстояние не влияют
+	for len(ra) > 0 && len(rb) > 0 && ra[0] == rb[0] {
+		ra, rb = ra[1:], rb[1:]
+	}
+	for len(ra) > 0 && len(rb) > 0 && ra[len(ra)-1] == rb[len(rb)-1] {
+		ra, rb = ra[:len(ra)-1], rb[:len(rb)-1]
 	}
-	for j := 0; j <= bl; j++ {
-		dp[0][j] = j
+	// строки DP — по более короткой строке
+	if len(ra) < len(rb) {
+		ra, rb = rb, ra
+	}
+	n, m := len(ra), len(rb)
+	if limit >= 0 && n-m > limit {
+		return limit + 1
+	}
+	if m == 0 {
+		return n
 	}
 
-	for i := 1; i <= al; i++ {
-		for j := 1; j <= bl; j++ {
+	buf.prev2 = growInts(buf.prev2, m+1)
+	buf.prev = growInts(buf.prev, m+1)
+	buf.cur = growInts(buf.cur, m+1)
+	prev2, prev, cur := buf.prev2, buf.prev, buf.cur
+	for j := 0; j <= m; j++ {
+		prev[j] = j
+	}
+
+	for i := 1; i <= n; i++ {
+		cur[0] = i
+		rowMin := i
+		for j := 1; j <= m; j++ {
 			cost := 0
 			if ra[i-1] != rb[j-1] {
 				cost = 1
 			}
 			// вставка / удаление / замена
-			dp[i][j] = min3(dp[i-1][j]+1, dp[i][j-1]+1, dp[i-1][j-1]+cost)
+			v := min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
 
 			// транспозиция соседних символов
 			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
-				if v := dp[i-2][j-2] + 1; v < dp[i][j] {
-					dp[i][j] = v
+				if t := prev2[j-2] + 1; t < v {
+					v = t
 				}
 			}
+			cur[j] = v
+			if v < rowMin {
+				rowMin = v
+			}
+		}
+		if limit >= 0 && rowMin > limit {
+			return limit + 1
 		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	if d := prev[m]; limit < 0 || d <= limit {
+		return d
 	}
-	return dp[al][bl]
+	return limit + 1
 }
 
 func min(a, b int) int {
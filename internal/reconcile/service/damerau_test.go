@@ -0,0 +1,107 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// Синтетические наименования 1С типичной длины 30–70 символов: габариты,
+// ГОСТ/DIN, сокращения.
+var benchTemplates = []func(r *rand.Rand) string{
+	func(r *rand.Rand) string {
+		return fmt.Sprintf("Поддон деревянный %dх%d мм, %d сорт (б/у)",
+			benchPick(r, 1200, 1000, 800), benchPick(r, 800, 1000, 600), 1+r.Intn(3))
+	},
+	func(r *rand.Rand) string {
+		return fmt.Sprintf("Саморез по дереву %d,%dх%d оцинк. (уп. %d шт)",
+			3+r.Intn(3), 2+r.Intn(8), 25+5*r.Intn(20), benchPick(r, 100, 200, 500))
+	},
+	func(r *rand.Rand) string {
+		return fmt.Sprintf("Болт М%dх%d DIN 933 кл.пр. 8.8 оцинкованный",
+			benchPick(r, 6, 8, 10, 12, 16), 20+10*r.Intn(10))
+	},
+	func(r *rand.Rand) string {
+		return fmt.Sprintf("Кабель ВВГнг(А)-LS %dх%d ок(N)-0,66 ГОСТ 31996-2012",
+			benchPick(r, 2, 3, 4, 5), benchPick(r, 2, 4, 6, 10))
+	},
+	func(r *rand.Rand) string {
+		return fmt.Sprintf("Плёнка стрейч %d мкм х %d мм, %d м, первичное сырьё",
+			benchPick(r, 17, 20, 23), benchPick(r, 250, 500), benchPick(r, 150, 200, 300))
+	},
+	func(r *rand.Rand) string {
+		return fmt.Sprintf("Краска ВД-АК-%d белая матовая %d кг арт.%05d",
+			benchPick(r, 101, 201, 1180), benchPick(r, 3, 7, 14, 25), r.Intn(100000))
+	},
+}
+
+func benchPick(r *rand.Rand, v ...int) int { return v[r.Intn(len(v))] }
+
+// benchTypo — одна опечатка: перестановка соседних, пропуск или замена
+func benchTypo(r *rand.Rand, s string) string {
+	rs := []rune(s)
+	i := 1 + r.Intn(len(rs)-2)
+	switch r.Intn(3) {
+	case 0:
+		rs[i], rs[i+1] = rs[i+1], rs[i]
+	case 1:
+		rs = append(rs[:i], rs[i+1:]...)
+	default:
+		rs[i] = 'о'
+	}
+	return string(rs)
+}
+
+// benchPairs — нормализованные пары: половина — то же имя с опечаткой,
+// половина — случайное другое имя (ранний выход по порогу).
+func benchPairs(n int) [][2]string {
+	r := rand.New(rand.NewSource(1))
+	names := make([]string, n)
+	for i := range names {
+		names[i] = NameKey(benchTemplates[r.Intn(len(benchTemplates))](r))
+	}
+	pairs := make([][2]string, n)
+	for i, a := range names {
+		b := names[r.Intn(n)]
+		if i%2 == 0 {
+			b = benchTypo(r, a)
+		}
+		pairs[i] = [2]string{a, b}
+	}
+	return pairs
+}
+
+func BenchmarkDamerauBounded(b *testing.B) {
+	pairs := benchPairs(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := pairs[i%len(pairs)]
+		damerauBounded(p[0], p[1], 8)
+	}
+}
+
+func BenchmarkBestSimilarityMin(b *testing.B) {
+	pairs := benchPairs(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := pairs[i%len(pairs)]
+		bestSimilarityMin(p[0], p[1], 0.83)
+	}
+}
+
+// BenchmarkScoreCache — повторные оценки тех же пар (как в chooseBest после fuzzy)
+func BenchmarkScoreCache(b *testing.B) {
+	pairs := benchPairs(1000)
+	c := newScoreCache(damerauSimilarity{})
+	for _, p := range pairs {
+		c.Score(p[0], p[1])
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := pairs[i%len(pairs)]
+		c.Score(p[0], p[1])
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// --------- ГАБАРИТЫ: 1200x800, 1200х800x144, 12,5×30, 120*80 см ---------
+
+// dimOptions — модель габаритов
+type dimOptions struct {
+	Sort  bool // грани по убыванию: 800x1200 == 1200x800
+	Third bool // оставлять третью грань; иначе берём первые две (паллеты)
+}
+
+// группа из 2–3 граней; грань — целое или десятичное число
+const reFace = `(\d{1,5}(?:[.,]\d{1,3})?)`
+
+var reDim = regexp.MustCompile(reFace + `\s*[xх×\*XХ]\s*` + reFace + `(?:\s*[xх×\*XХ]\s*` + reFace + `)?`)
+
+// единицы после габарита → множитель к мм
+var dimUnits = []struct {
+	alias  string
+	factor float64
+}{
+	{"мм", 1}, {"mm", 1}, {"см", 10}, {"cm", 10}, {"м", 1000}, {"m", 1000},
+}
+
+// reDimToken — габарит в уже нормализованном ключе (см. dimToken)
+var reDimToken = regexp.MustCompile(`^\d+(?:\.\d+)?(?:x\d+(?:\.\d+)?){1,2}$`)
+
+// normalizeDims вытаскивает все группы габаритов и возвращает их токены
+// (грани в мм, "1200x800") и строку без них.
+func normalizeDims(s string, opt dimOptions) (dims []string, out string) {
+	locs := reDim.FindAllStringSubmatchIndex(s, -1)
+	if len(locs) == 0 {
+		return nil, strings.TrimSpace(s)
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, loc := range locs {
+		start, end := loc[0], loc[1]
+		// габарит — отдельное слово: не "м8x40", не "1.1200x800", не "1200x8000"
+		if start > 0 {
+			if r := lastRune(s[:start]); unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == ',' {
+				continue
+			}
+		}
+		if end < len(s) && unicode.IsDigit(firstRune(s[end:])) {
+			continue
+		}
+
+		var faces []float64
+		for g := 1; g <= 3; g++ {
+			if loc[2*g] < 0 {
+				continue
+			}
+			v, err := strconv.ParseFloat(strings.ReplaceAll(s[loc[2*g]:loc[2*g+1]], ",", "."), 64)
+			if err != nil {
+				faces = nil
+				break
+			}
+			faces = append(faces, v)
+		}
+		if len(faces) < 2 {
+			continue
+		}
+
+		// необязательная единица сразу за габаритом
+		factor := 1.0
+		rest := s[end:]
+		trimmed := strings.TrimLeft(rest, " ")
+		for _, u := range dimUnits {
+			if !strings.HasPrefix(trimmed, u.alias) {
+				continue
+			}
+			after := trimmed[len(u.alias):]
+			if after != "" && unicode.IsLetter(firstRune(after)) {
+				continue
+			}
+			factor = u.factor
+			end += len(rest) - len(trimmed) + len(u.alias)
+			break
+		}
+		for i := range faces {
+			faces[i] *= factor
+		}
+
+		dims = append(dims, dimToken(faces, opt))
+		sb.WriteString(s[last:start])
+		sb.WriteByte(' ')
+		last = end
+	}
+	sb.WriteString(s[last:])
+	return dims, strings.TrimSpace(sb.String())
+}
+
+// dimToken — канонический токен габарита по модели opt
+func dimToken(faces []float64, opt dimOptions) string {
+	if !opt.Third && len(faces) > 2 {
+		faces = faces[:2]
+	}
+	if opt.Sort {
+		sort.Sort(sort.Reverse(sort.Float64Slice(faces)))
+	}
+	parts := make([]string, len(faces))
+	for i, f := range faces {
+		parts[i] = strconv.FormatFloat(math.Round(f*1e3)/1e3, 'f', -1, 64)
+	}
+	return strings.Join(parts, "x")
+}
+
+// parseDimToken разбирает токен габарита из ключа обратно в грани
+func parseDimToken(t string) ([]float64, bool) {
+	if !reDimToken.MatchString(t) {
+		return nil, false
+	}
+	parts := strings.Split(t, "x")
+	faces := make([]float64, 0, len(parts))
+	for _, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return nil, false
+		}
+		faces = append(faces, v)
+	}
+	return faces, true
+}
+
+// sameDims — габариты совпадают; если у одной стороны на грань меньше
+// (высоту не указали), её грани должны входить в другую по порядку.
+func sameDims(a, b []float64) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(b)-len(a) > 1 {
+		return false
+	}
+	i := 0
+	for _, f := range b {
+		if i < len(a) && math.Abs(a[i]-f) <= unitTolerance*math.Max(a[i], f) {
+			i++
+		}
+	}
+	return i == len(a)
+}
+
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+	return 0
+}
+
+func lastRune(s string) rune {
+	rs := []rune(s)
+	if len(rs) == 0 {
+		return 0
+	}
+	return rs[len(rs)-1]
+}
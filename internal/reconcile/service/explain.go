@@ -0,0 +1,109 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"recon-service/internal/reconcile/model"
+)
+
+// --------- EXPLAIN: кандидаты и причины отказа (opt.Explain) ---------
+
+// Причины, по которым кандидат B не стал парой строки A
+const (
+	reasonUnitsGuard     = "units_guard"     // не совпали количества с единицами (equalNumUnitsSoft)
+	reasonBelowThreshold = "below_threshold" // схожесть не выше opt.Threshold
+	reasonAlreadyUsed    = "already_used"    // строку B забрала другая строка A
+	reasonStrictMode     = "strict_mode"     // прошёл бы fuzzy, но fuzzy выключен / strict_after_norm
+	reasonOutranked      = "outranked"       // свободен и допустим, но выбран кандидат лучше
+)
+
+// defaultExplainTop — сколько кандидатов показывать, если opt.ExplainTop не задан
+const defaultExplainTop = 5
+
+// explainAll считает для каждой строки A кандидатов, которых рассматривал
+// каскад (SKU, точное имя, top-K триграмм), с оценками и причинами отказа.
+// Вызывается после назначения: matches и usedB — итоговые.
+func explainAll(a, b []model.Row, idx *Index, sim Similarity, opt model.Options, matches []match, usedB []bool) [][]model.Candidate {
+	top := opt.ExplainTop
+	if top <= 0 {
+		top = defaultExplainTop
+	}
+	fs := newFuzzyScorer(idx, opt, sim)
+	fuzzyOn := opt.EnableFuzzy && !opt.StrictAfterNorm
+
+	out := make([][]model.Candidate, len(a))
+	for i, ar := range a {
+		var cands []model.Candidate
+		seen := make(map[int]bool)
+		nuA := extractNumUnits(ar.NameNorm, dictOf(opt))
+
+		add := func(j int, pass string) {
+			if seen[j] {
+				return
+			}
+			seen[j] = true
+			br := b[j]
+			c := model.Candidate{
+				IDB:   br.ID,
+				Name:  br.Name,
+				Sku:   br.Sku,
+				Qty:   br.Qty,
+				Pass:  pass,
+				Score: sim.Score(ar.NameNorm, br.NameNorm),
+			}
+			switch {
+			case matches[i].b == j:
+				c.Chosen = true
+			case pass == passFuzzy && !equalNumUnitsSoft(nuA, fs.units[br.NameNorm]):
+				c.Reason = reasonUnitsGuard
+			case pass == passFuzzy && c.Score <= opt.Threshold:
+				c.Reason = reasonBelowThreshold
+			case pass == passFuzzy && !fuzzyOn:
+				c.Reason = reasonStrictMode
+			case usedB[j]:
+				c.Reason = reasonAlreadyUsed
+			default:
+				c.Reason = reasonOutranked
+			}
+			cands = append(cands, c)
+		}
+
+		for _, j := range idx.crosswalkTargets(ar.SkuNorm) {
+			add(j, passCrosswalk)
+		}
+		if s := ar.SkuNorm; s != "" {
+			for _, j := range idx.bySku[s] {
+				add(j, passSku)
+			}
+		}
+		if strings.TrimSpace(ar.NameNorm) != "" {
+			for _, j := range idx.byName[ar.NameNorm] {
+				add(j, passExact)
+			}
+			// те же кандидаты, что видел fuzzy-проход (без отсечки по порогу
+			// пересечения — иначе ниже порога нечего было бы показать)
+			for _, name := range idx.candidateNames(ar.NameNorm, opt.TopK, 0) {
+				for _, j := range idx.byName[name] {
+					add(j, passFuzzy)
+				}
+			}
+		}
+
+		// выбранный — первым, остальные по убыванию схожести
+		sort.SliceStable(cands, func(x, y int) bool {
+			if cands[x].Chosen != cands[y].Chosen {
+				return cands[x].Chosen
+			}
+			if cands[x].Score != cands[y].Score {
+				return cands[x].Score > cands[y].Score
+			}
+			return cands[x].IDB < cands[y].IDB
+		})
+		if len(cands) > top {
+			cands = cands[:top]
+		}
+		out[i] = cands
+	}
+	return out
+}
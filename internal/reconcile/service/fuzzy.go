@@ -0,0 +1,100 @@
+package service
+
+import (
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"recon-service/internal/reconcile/model"
+)
+
+// scoredName — кандидат из B (нормализованное имя) с оценкой схожести
+type scoredName struct {
+	name  string
+	score float64
+}
+
+// fuzzyScorer ранжирует кандидатов B для fuzzy-прохода. «Число+единица»
+// кандидатов считаются один раз при создании, дальше структура только
+// читается и безопасна для параллельного использования.
+type fuzzyScorer struct {
+	idx   *Index
+	opt   model.Options
+	sim   Similarity           // метрика запроса (opt.Metric)
+	units map[string][]numUnit // candNorm -> число+единица
+}
+
+func newFuzzyScorer(idx *Index, opt model.Options, sim Similarity) *fuzzyScorer {
+	fs := &fuzzyScorer{
+		idx:   idx,
+		opt:   opt,
+		sim:   sim,
+		units: make(map[string][]numUnit, len(idx.byName)),
+	}
+	// ключи byName уже нормализованы с теми же опциями — повторно не гоняем
+	for name := range idx.byName {
+		fs.units[name] = extractNumUnits(name, dictOf(opt))
+	}
+	return fs
+}
+
+// rank возвращает кандидатов выше порога, прошедших guard по единицам:
+// по убыванию схожести, при равенстве — по имени (детерминизм).
+func (fs *fuzzyScorer) rank(norm string) []scoredName {
+	if strings.TrimSpace(norm) == "" {
+		return nil
+	}
+	nuA := extractNumUnits(norm, dictOf(fs.opt))
+
+	// только top-K по пересечению триграмм: без полного прохода по B
+	cands := fs.idx.candidateNames(norm, fs.opt.TopK, trigramCutoff(fs.opt, fs.opt.Threshold))
+
+	var out []scoredName
+	for _, name := range cands {
+		if !equalNumUnitsSoft(nuA, fs.units[name]) {
+			continue
+		}
+		s := scoreMin(fs.sim, norm, name, fs.opt.Threshold)
+		if s > fs.opt.Threshold {
+			out = append(out, scoredName{name: name, score: s})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].score != out[j].score {
+			return out[i].score > out[j].score
+		}
+		return out[i].name < out[j].name
+	})
+	return out
+}
+
+// rankAll ранжирует кандидатов для строк A с pending[i] == true в пуле
+// воркеров по числу CPU. Каждая строка пишет только в свой слот.
+func (fs *fuzzyScorer) rankAll(a []model.Row, pending []bool) [][]scoredName {
+	out := make([][]scoredName, len(a))
+
+	nw := runtime.NumCPU()
+	if nw < 1 {
+		nw = 1
+	}
+	jobs := make(chan int, nw*2)
+	var wg sync.WaitGroup
+	for w := 0; w < nw; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				out[i] = fs.rank(a[i].NameNorm)
+			}
+		}()
+	}
+	for i := range a {
+		if pending[i] {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return out
+}
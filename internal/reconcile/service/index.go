@@ -1,47 +1,79 @@
 package service
 
 import (
+	"container/heap"
 	"math"
 	"sort"
 	"strings"
+	"sync"
+	"unicode/utf8"
 
 	"recon-service/internal/reconcile/model"
 )
 
-// индекс для быстрого поиска по B
+// индекс для быстрого поиска по B; списки содержат Row.ID (позиции в B)
 type Index struct {
-	bySku  map[string][]model.Row
-	byName map[string][]model.Row
-	inv    map[string]map[string]struct{} // trigram -> set(normalized name)
+	bySku  map[string][]int
+	byName map[string][]int
+	names  []string           // различные нормализованные имена B (порядок первых вхождений)
+	grams  []int              // names[k] -> число его триграмм
+	lens   []int              // names[k] -> длина в рунах
+	slack  []int              // names[k] -> запас триграмм на перестановку токенов (reorderSlack)
+	inv    map[string][]int32 // trigram -> номера имён в names
+	df     map[string]int     // токен -> в скольких различных именах B встречается
+	cw     crosswalk          // артикул A -> артикулы B по таблице соответствия (Run)
+
+	overlap sync.Pool // *overlapBuf — счётчики пересечений для candidateNames
+}
+
+// overlapBuf — счётчики общих триграмм по номерам имён и список
+// затронутых номеров (для обнуления без прохода по всему массиву)
+type overlapBuf struct {
+	counts  []int32
+	touched []int32
 }
 
+// buildIndexB строит индекс и проставляет строкам B стабильные ID
+// (позиция в rows), по которым дальше учитывается их использование.
+// Заодно считает документные частоты токенов для метрики idf.
 func buildIndexB(rows []model.Row) *Index {
 	idx := &Index{
-		bySku:  make(map[string][]model.Row),
-		byName: make(map[string][]model.Row),
-		inv:    make(map[string]map[string]struct{}),
+		bySku:  make(map[string][]int),
+		byName: make(map[string][]int),
+		inv:    make(map[string][]int32),
+		df:     make(map[string]int),
 	}
 
-	for _, r := range rows {
-		if r.Sku != "" {
-			idx.bySku[r.Sku] = append(idx.bySku[r.Sku], r)
+	for i := range rows {
+		rows[i].ID = i
+		r := rows[i]
+		if s := r.SkuNorm; s != "" {
+			idx.bySku[s] = append(idx.bySku[s], i)
 		}
 		if r.NameNorm == "" {
 			continue
 		}
 		nn := r.NameNorm
-		idx.byName[nn] = append(idx.byName[nn], r)
-
-		for g := range trigramSet(nn) {
-			bucket, ok := idx.inv[g]
-			if !ok {
-				bucket = make(map[string]struct{})
-				idx.inv[g] = bucket
+		if _, seen := idx.byName[nn]; !seen {
+			// частоты токенов и триграммы — по различным именам
+			for t := range tokenBag(nn) {
+				idx.df[t]++
+			}
+			k := int32(len(idx.names))
+			gs := trigramSet(nn)
+			idx.names = append(idx.names, nn)
+			idx.grams = append(idx.grams, len(gs))
+			idx.lens = append(idx.lens, utf8.RuneCountInString(nn))
+			idx.slack = append(idx.slack, reorderSlack(nn))
+			for g := range gs {
+				idx.inv[g] = append(idx.inv[g], k)
 			}
-			bucket[nn] = struct{}{}
 		}
+		idx.byName[nn] = append(idx.byName[nn], i)
 	}
 
+	n := len(idx.names)
+	idx.overlap.New = func() any { return &overlapBuf{counts: make([]int32, n)} }
 	return idx
 }
 
@@ -63,48 +95,146 @@ func trigramSet(s string) map[string]struct{} {
 	return m
 }
 
-func (idx *Index) candidateNames(norm string) []string {
+// candidate — имя B и его пересечение с запросом по триграммам
+type candidate struct {
+	name    string
+	overlap int
+	jaccard float64
+}
+
+// better — детерминированный порядок кандидатов
+func (c candidate) better(o candidate) bool {
+	if c.jaccard != o.jaccard {
+		return c.jaccard > o.jaccard
+	}
+	if c.overlap != o.overlap {
+		return c.overlap > o.overlap
+	}
+	return c.name < o.name
+}
+
+// candHeap — куча с худшим кандидатом на вершине (отбор top-K)
+type candHeap []candidate
+
+func (h candHeap) Len() int           { return len(h) }
+func (h candHeap) Less(i, j int) bool { return h[j].better(h[i]) }
+func (h candHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *candHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *candHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// candidateNames возвращает не больше topK имён B (topK <= 0 — без
+// ограничения), ранжированных по Jaccard триграмм с запросом, затем по
+// числу общих триграмм и по имени.
+//
+// threshold > 0 включает отсечку по минимальному пересечению — она верна
+// только для damerau (см. trigramCutoff), для остальных метрик передают 0.
+// При схожести ≥ threshold правок d ≤ (1-threshold)·max(len), а одна правка
+// (в том числе перестановка соседних букв) портит не больше четырёх
+// триграмм. Поэтому общих триграмм не меньше min(|A|,|B|) - 4d; запас
+// reorderSlack покрывает сравнение по отсортированным токенам (bestSimilarity).
+// Берём меньший набор, чтобы не терять имена-подмножества.
+func (idx *Index) candidateNames(norm string, topK int, threshold float64) []string {
 	if norm == "" {
 		return nil
 	}
-	seen := make(map[string]struct{})
-	for g := range trigramSet(norm) {
-		if bucket, ok := idx.inv[g]; ok {
-			for nn := range bucket {
-				seen[nn] = struct{}{}
+	q := trigramSet(norm)
+	buf := idx.overlap.Get().(*overlapBuf)
+	defer idx.overlap.Put(buf)
+	for g := range q {
+		for _, k := range idx.inv[g] {
+			if buf.counts[k] == 0 {
+				buf.touched = append(buf.touched, k)
 			}
+			buf.counts[k]++
 		}
 	}
-	out := make([]string, 0, len(seen))
-	for nn := range seen {
-		out = append(out, nn)
+
+	qLen, qSlack := utf8.RuneCountInString(norm), reorderSlack(norm)
+	var cands []candidate
+	for _, k := range buf.touched {
+		c := int(buf.counts[k])
+		buf.counts[k] = 0
+		if threshold > 0 {
+			small := min(idx.grams[k], len(q))
+			edits := int((1-threshold)*float64(max(qLen, idx.lens[k])) + 1e-9)
+			if c < small-4*edits-qSlack-idx.slack[k] {
+				continue
+			}
+		}
+		cand := candidate{
+			name:    idx.names[k],
+			overlap: c,
+			jaccard: float64(c) / float64(len(q)+idx.grams[k]-c),
+		}
+		// top-K держим в куче: худший из отобранных — на вершине
+		switch {
+		case topK <= 0:
+			cands = append(cands, cand)
+		case len(cands) < topK:
+			heap.Push((*candHeap)(&cands), cand)
+		case cand.better(cands[0]):
+			cands[0] = cand
+			heap.Fix((*candHeap)(&cands), 0)
+		}
+	}
+	buf.touched = buf.touched[:0]
+
+	sort.Slice(cands, func(i, j int) bool { return cands[i].better(cands[j]) })
+
+	out := make([]string, len(cands))
+	for i, c := range cands {
+		out[i] = c.name
 	}
-	sort.Strings(out) // для детерминированного порядка
 	return out
 }
 
 func similarity(a, b string) float64 {
-	// normalized Damerau-Levenshtein similarity in [0..1]
-	if a == "" && b == "" {
+	return similarityMin(a, b, 0)
+}
+
+// similarityMin — нормированная схожесть Дамерау–Левенштейна в [0..1] с
+// ранним выходом: если она заведомо не выше min, счёт обрывается и
+// возвращается оценка снизу (< min). Точное значение гарантировано
+// только для результатов выше min.
+func similarityMin(a, b string, min float64) float64 {
+	if a == b {
 		return 1
 	}
 	if a == "" || b == "" {
 		return 0
 	}
-	d := damerauLevenshtein(a, b)
-	m := len([]rune(a))
-	if mb := len([]rune(b)); mb > m {
+	m := utf8.RuneCountInString(a)
+	if mb := utf8.RuneCountInString(b); mb > m {
 		m = mb
 	}
-	if m == 0 {
-		return 1
+	limit := -1
+	if min > 0 {
+		// sim > min ⇔ d < (1-min)·m; эпсилон — от ошибок округления
+		limit = int((1-min)*float64(m) + 1e-9)
 	}
+	d := damerauBounded(a, b, limit)
 	return 1 - float64(d)/float64(m)
 }
 
+// reorderSlack — на сколько триграмм имя может разойтись со своей
+// версией с отсортированными токенами: меняются только тройки с пробелом
+// посередине, по одной на пробел; ×2 — оценка и для набора, и для
+// пересечения. Уже отсортированное имя — 0.
+func reorderSlack(s string) int {
+	if tokensSorted(s) {
+		return 0
+	}
+	return 2 * strings.Count(s, " ")
+}
+
 // tokenSort: сортируем токены по алфавиту (устойчиво к порядку слов)
 func tokenSort(s string) string {
-	if s == "" {
+	if s == "" || tokensSorted(s) {
 		return s
 	}
 	t := strings.Fields(s)
@@ -112,6 +242,28 @@ func tokenSort(s string) string {
 	return strings.Join(t, " ")
 }
 
+// tokensSorted — уже ли токены по алфавиту (ключи с TokenSort — да);
+// проверка без аллокаций
+func tokensSorted(s string) bool {
+	prev := ""
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return true
+		}
+		tok := s
+		if i := strings.IndexByte(s, ' '); i >= 0 {
+			tok, s = s[:i], s[i:]
+		} else {
+			s = ""
+		}
+		if tok < prev {
+			return false
+		}
+		prev = tok
+	}
+}
+
 func tokenSortSimilarity(a, b string) float64 {
 	sa := tokenSort(a)
 	sb := tokenSort(b)
@@ -119,13 +271,20 @@ func tokenSortSimilarity(a, b string) float64 {
 }
 
 func bestSimilarity(a, b string) float64 {
-	x := similarity(a, b)
-	y := tokenSortSimilarity(a, b)
-	if y > x {
+	return bestSimilarityMin(a, b, 0)
+}
+
+// bestSimilarityMin — max(DL, DL по отсортированным токенам) с ранним
+// выходом по min (см. similarityMin). Если токены обеих строк уже
+// отсортированы, второй расчёт не нужен.
+func bestSimilarityMin(a, b string, min float64) float64 {
+	x := similarityMin(a, b, min)
+	sa, sb := tokenSort(a), tokenSort(b)
+	if sa == a && sb == b {
+		return x
+	}
+	if y := similarityMin(sa, sb, math.Max(min, x)); y > x {
 		return y
 	}
 	return x
 }
-
-// silence unused import warning (when damerauLevenshtein comes from another file)
-var _ = math.Min
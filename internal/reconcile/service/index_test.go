@@ -0,0 +1,72 @@
+package service
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"recon-service/internal/reconcile/model"
+)
+
+// Перестановки соседних букв портят до четырёх триграмм на правку:
+// отсечка candidateNames не должна выкидывать имя, проходящее порог.
+func TestCandidateNamesKeepsTranspositions(t *testing.T) {
+	const a, b = "саморез оцинкованный", "асморез оицнкованынй"
+	if s := bestSimilarity(a, b); s < 0.83 {
+		t.Fatalf("bestSimilarity = %.3f, ждём не ниже 0.83", s)
+	}
+	idx := buildIndexB([]model.Row{{Name: b, NameNorm: b}})
+	if got := idx.candidateNames(a, 50, 0.83); !slices.Contains(got, b) {
+		t.Errorf("candidateNames(%q) = %q, ждём %q", a, got, b)
+	}
+}
+
+// Отсечка безопасна: всё, что проходит порог по damerau, остаётся в кандидатах.
+func TestCandidateNamesCutoffIsSafe(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []rune("абвгдеклмнор ")
+	mutate := func(s []rune) []rune {
+		s = slices.Clone(s)
+		switch i := rng.Intn(len(s)); rng.Intn(4) {
+		case 0:
+			s[i] = alphabet[rng.Intn(len(alphabet))]
+		case 1:
+			s = slices.Delete(s, i, i+1)
+		case 2:
+			s = slices.Insert(s, i, alphabet[rng.Intn(len(alphabet))])
+		default:
+			if i+1 < len(s) {
+				s[i], s[i+1] = s[i+1], s[i]
+			}
+		}
+		return s
+	}
+	for n := 0; n < 2000; n++ {
+		q := make([]rune, 8+rng.Intn(30))
+		for i := range q {
+			q[i] = alphabet[rng.Intn(len(alphabet)-1)]
+			if i > 0 && i%(4+n%5) == 0 {
+				q[i] = ' '
+			}
+		}
+		c := q
+		for e := rng.Intn(4); e >= 0; e-- {
+			if len(c) > 1 {
+				c = mutate(c)
+			}
+		}
+		qs, cs := string(q), string(c)
+		if qs == cs {
+			continue
+		}
+		for _, th := range []float64{0.6, 0.75, 0.83, 0.9} {
+			if bestSimilarity(qs, cs) < th {
+				continue
+			}
+			idx := buildIndexB([]model.Row{{Name: cs, NameNorm: cs}})
+			if got := idx.candidateNames(qs, 0, th); !slices.Contains(got, cs) {
+				t.Fatalf("порог %.2f: %q потерян для %q", th, cs, qs)
+			}
+		}
+	}
+}
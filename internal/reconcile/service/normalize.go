@@ -1,11 +1,12 @@
 package service
 
 import (
-	"regexp"
 	"sort"
 	"strings"
 	"unicode"
 	"strconv"
+
+	"recon-service/internal/reconcile/dictionary"
 )
 
 // --- базовая нормализация текста ---
@@ -25,52 +26,10 @@ func toLowerRu(s string) string {
 	return s
 }
 
-// --- извлечение и унификация размеров ---
-
-// 1200x800, 1200х800, 1200×800, 1200*800, + необязательная третья грань и суффикс мм/mm
-var reDim = regexp.MustCompile(`(?i)\b(\d{2,5})\s*[xх×\*]\s*(\d{2,5})(?:\s*[xх×\*]\s*(\d{1,5}))?\s*(?:мм|mm)?\b`)
-
-// normalizeDims возвращает нормализованный токен размера (пример: "1200x800")
-// Если находит 3-мерный размер, возвращает первые две грани — этого обычно достаточно для паллетов.
-func normalizeDims(s string) (dim string, out string) {
-	out = s
-	m := reDim.FindStringSubmatch(s)
-	if len(m) >= 3 {
-		a := strings.TrimLeft(m[1], "0")
-		b := strings.TrimLeft(m[2], "0")
-		if a == "" { a = "0" }
-		if b == "" { b = "0" }
-		dim = a + "x" + b
-		// вырезаем исходный фрагмент размера из строки, чтобы не мешал токенизации
-		out = strings.Replace(s, m[0], " ", 1)
-	}
-	return dim, strings.TrimSpace(out)
-}
-
-// --- синонимы/канонизация товарных слов ---
-
-// единичные замены токенов
-var tokenSynonyms = map[string]string{
-	// базовые синонимы для контекста паллет/поддонов
-	"паллет":   "поддон",
-	"палета":   "поддон",
-	"паллета":  "поддон",
-	"паллетта": "поддон",
-	"палет":    "поддон",
-	// ед. изм. и мусор — выкинем позже как стоп-слова, но на всякий случай канонизируем
-	"шт.": "шт",
-	"л.":  "л",
-}
+// --- извлечение и унификация размеров: см. dims.go ---
 
-// выражения «евро поддон» в любых формах → один токен "европоддон"
-var reEuroPoddon1 = regexp.MustCompile(`\bевро\s*[-\s]*поддон\b`)
-var reEuroPoddon2 = regexp.MustCompile(`\bподдон\s*[-\s]*евро\b`)
-var reEuroPoddon3 = regexp.MustCompile(`\bевроподдон\b`)
-
-// стоп-слова, не влияющие на сущность
-var stop = map[string]struct{}{
-	"мм": {}, "mm": {}, "шт": {}, "уп": {}, "упак": {}, "ед": {}, "изм": {},
-}
+// Синонимы, фразовые замены («евро поддон» → «европоддон»), стоп-слова и
+// единицы измерения берутся из словаря (см. internal/reconcile/dictionary).
 
 // --- токенизация с учётом кириллицы и цифр ---
 
@@ -85,13 +44,18 @@ func splitTokens(s string) []string {
 		sb.Reset()
 		tokens = append(tokens, t)
 	}
-	for _, r := range s {
+	rs := []rune(s)
+	for i, r := range rs {
 		switch {
 		case unicode.IsLetter(r), unicode.IsDigit(r):
 			// кириллица/латиница/цифры — ок
 			sb.WriteRune(r)
 		case r == 'x': // оставим 'x' как часть размеров, но тут таких уже нет (мы их вырезали ранее)
 			sb.WriteRune(r)
+		case (r == ',' || r == '.') && i > 0 && i+1 < len(rs) &&
+			unicode.IsDigit(rs[i-1]) && unicode.IsDigit(rs[i+1]):
+			// десятичная дробь "1,5" остаётся одним токеном "1.5"
+			sb.WriteRune('.')
 		default:
 			flush()
 		}
@@ -100,47 +64,127 @@ func splitTokens(s string) []string {
 	return tokens
 }
 
-// --- публичная функция нормализации названия ---
+// isNumberToken: "10", "1.5" (после splitTokens дробь уже через точку)
+func isNumberToken(t string) bool {
+	_, err := strconv.ParseFloat(t, 64)
+	return err == nil && strings.IndexFunc(t, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	}) < 0
+}
 
-// NameKey строит порядконезависимый нормализованный ключ номенклатуры.
-// Примеры (все дадут один ключ):
-//  - "Поддон Евро 1200х800мм"
-//  - "Европоддон 1200×800"
-//  - "евро-поддон 1200*800 мм"
-//  => "1200x800 европоддон"
-func NameKey(raw string) string {
+// --- двойники латиница→кириллица ---
+
+// homoglyphs — латинские буквы, визуально совпадающие с кириллическими
+var homoglyphs = map[rune]rune{
+	'A': 'А', 'a': 'а', 'B': 'В', 'C': 'С', 'c': 'с', 'E': 'Е', 'e': 'е',
+	'H': 'Н', 'K': 'К', 'k': 'к', 'M': 'М', 'O': 'О', 'o': 'о', 'P': 'Р',
+	'p': 'р', 'T': 'Т', 'X': 'Х', 'x': 'х', 'Y': 'У', 'y': 'у',
+}
+
+// unifyToken переводит латинские двойники в кириллицу, но только в токенах,
+// где уже есть кириллица ("Пoддон" с латинской o). Чисто латинские слова
+// (бренды, модели) не трогаем.
+func unifyToken(t string) string {
+	hasCyr := false
+	for _, r := range t {
+		if unicode.Is(unicode.Cyrillic, r) {
+			hasCyr = true
+			break
+		}
+	}
+	if !hasCyr {
+		return t
+	}
+	return strings.Map(func(r rune) rune {
+		if c, ok := homoglyphs[r]; ok {
+			return c
+		}
+		return r
+	}, t)
+}
+
+// --- единицы измерения в конце наименования ---
+
+// stripTrailingUnits срезает единицы измерения в конце списка токенов
+// ("Гвозди 100 мм шт" → "Гвозди 100")
+func stripTrailingUnits(tokens []string, dict *dictionary.Dictionary) []string {
+	for len(tokens) > 0 {
+		if !dict.IsUnit(toLowerRu(tokens[len(tokens)-1])) {
+			break
+		}
+		tokens = tokens[:len(tokens)-1]
+	}
+	return tokens
+}
+
+// --- конвейер нормализации ---
+
+// pipeline — набор этапов нормализации; каждый флаг включает/выключает
+// реальный этап (см. model.Options).
+type pipeline struct {
+	Normalization bool // чистка пунктуации, размеры, синонимы, стоп-слова
+	TokenSort     bool // порядконезависимый ключ
+	StripUnits    bool // срезать единицы измерения в конце
+	Unify         bool // латиница→кириллица в смешанных токенах
+	Lowercase     bool // нижний регистр, ё→е
+	Stemming      bool // основа слова (Snowball ru/en): "поддоны" → "поддон"
+
+	Dims dimOptions             // модель габаритов (порядок граней, третья грань)
+	Dict *dictionary.Dictionary // синонимы, замены, стоп-слова, единицы
+}
+
+// defaultPipeline — поведение NameKey
+var defaultPipeline = pipeline{
+	Normalization: true,
+	TokenSort:     true,
+	Lowercase:     true,
+	Dims:          dimOptions{Sort: true},
+	Dict:          dictionary.Builtin(),
+}
+
+// Key строит нормализованный ключ по включённым этапам.
+func (p pipeline) Key(raw string) string {
 	if raw == "" {
 		return ""
 	}
+	if p.Dict == nil {
+		p.Dict = dictionary.Builtin()
+	}
 
 	// 1) базовая чистка
 	s := spaceCleaner.Replace(raw)
-	s = toLowerRu(s)
-	// унификация знака размера: кириллическая 'х', умножение '×' → латинская 'x'
-	s = strings.NewReplacer("х", "x", "×", "x", "X", "x").Replace(s)
+	if p.Lowercase {
+		s = toLowerRu(s)
+	}
 	s = strings.TrimSpace(s)
 
-	// 2) вытащим размер
-	dim, rest := normalizeDims(s)
+	var dims []string
+	var rawTokens []string
+	if p.Normalization {
+		// 2) вытащим размеры (все группы; знак x/х/×/* распознаётся в dims.go)
+		var rest string
+		dims, rest = normalizeDims(s, p.Dims)
 
-	// 3) Канонизируем "европоддон"
-	rest = reEuroPoddon1.ReplaceAllString(rest, "европоддон")
-	rest = reEuroPoddon2.ReplaceAllString(rest, "европоддон")
-	// Если слово склеено, оставим как есть (редко встречается с дефисами/без пробела)
-	rest = strings.ReplaceAll(rest, "евро-поддон", "европоддон")
+		// 3) Фразовые замены словаря ("евро поддон" → "европоддон")
+		rest = p.Dict.Rewrite(rest)
 
-	// 4) Токенизация и синонимы
-	rawTokens := splitTokens(rest)
-	tokens := make([]string, 0, len(rawTokens)+1)
+		rawTokens = splitTokens(rest)
+	} else {
+		rawTokens = strings.Fields(s)
+	}
+
+	// 4) Единицы в конце — до синонимов и сортировки, пока порядок исходный
+	if p.StripUnits {
+		rawTokens = stripTrailingUnits(rawTokens, p.Dict)
+	}
 
+	// 5) Токены: двойники, синонимы, стоп-слова словаря и стемминг
+	tokens := make([]string, 0, len(rawTokens)+1)
 	seen := map[string]struct{}{}
 	add := func(t string) {
 		if t == "" {
 			return
 		}
-		if _, bad := stop[t]; bad {
-			return
-		}
 		if _, ok := seen[t]; ok {
 			return
 		}
@@ -148,31 +192,73 @@ func NameKey(raw string) string {
 		tokens = append(tokens, t)
 	}
 
-	for _, t := range rawTokens {
-		if rep, ok := tokenSynonyms[t]; ok {
-			t = rep
+	for i := 0; i < len(rawTokens); i++ {
+		t := rawTokens[i]
+		if p.Unify {
+			t = unifyToken(t)
+		}
+		if !p.Normalization {
+			tokens = append(tokens, t)
+			continue
+		}
+		// число остаётся в ключе ("Болт 10" ≠ "Болт 12"); если за ним идёт
+		// единица — склеиваем, чтобы "100 мм" и "100мм" дали один токен
+		if isNumberToken(t) {
+			if i+1 < len(rawTokens) {
+				if u := toLowerRu(rawTokens[i+1]); p.Dict.IsUnit(u) {
+					t += u
+					i++
+				}
+			}
+		}
+		// количество с единицей — в базовых единицах: "1,5 л" и "1500 мл"
+		// дают один токен "1500ml"
+		if q, ok := parseNumUnit(t, p.Dict); ok {
+			add(q.key())
+			continue
 		}
-		// склеенные варианты "европоддон" уже превращены выше, проверим ещё раз
-		if reEuroPoddon3.MatchString(t) {
-			t = "европоддон"
+		if isNumberToken(t) {
+			add(t)
+			continue
 		}
-		// выкинем чисто цифровые хвосты, которые уже «ушли» в размер
-		if _, err := strconv.Atoi(t); err == nil {
+		lt := toLowerRu(t)
+		if rep, ok := p.Dict.Synonym(lt); ok {
+			t = rep
+		}
+		if p.Dict.IsStop(lt) {
 			continue
 		}
+		// стемминг — после синонимов и стоп-слов: словарь пишется словами
+		if p.Stemming {
+			t = stemToken(t)
+		}
 		add(t)
 	}
 
-	// 5) Добавим размер отдельным токеном (если был)
-	if dim != "" {
-		add(dim)
+	// 6) Добавим размеры отдельными токенами (если были)
+	for _, d := range dims {
+		add(d)
 	}
 
 	if len(tokens) == 0 {
 		return ""
 	}
 
-	// 6) Порядконезависимый ключ
-	sort.Strings(tokens)
+	// 7) Порядконезависимый ключ
+	if p.TokenSort {
+		sort.Strings(tokens)
+	}
 	return strings.Join(tokens, " ")
 }
+
+// --- публичная функция нормализации названия ---
+
+// NameKey строит порядконезависимый нормализованный ключ номенклатуры.
+// Примеры (все дадут один ключ):
+//  - "Поддон Евро 1200х800мм"
+//  - "Европоддон 1200×800"
+//  - "евро-поддон 1200*800 мм"
+//  => "1200x800 европоддон"
+func NameKey(raw string) string {
+	return defaultPipeline.Key(raw)
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"recon-service/internal/decimal"
+	"recon-service/internal/reconcile/model"
+)
+
+// --------- КОЛИЧЕСТВА: допуск и статус строки ---------
+
+// Статусы строки сверки (ResultRow.Status)
+const (
+	StatusMatch           = "match"            // количества равны
+	StatusWithinTolerance = "within_tolerance" // расходятся, но в пределах допуска
+	StatusSurplus         = "surplus"          // в A больше, чем в B
+	StatusShortage        = "shortage"         // в A меньше, чем в B
+)
+
+var statusOrder = []string{StatusMatch, StatusWithinTolerance, StatusSurplus, StatusShortage}
+
+// roundQty приводит количества агрегатов к точности запроса
+// (opt.QtyPrecision < 0 — оставить как в файле). Округляем после слияния
+// дублей: сумма считается по точным значениям файла, и остатки округления
+// отдельных строк не накапливаются. Дельты дальше считаются точно.
+func roundQty(rows []model.Row, dups []model.DuplicateGroup, opt model.Options) {
+	if opt.QtyPrecision < 0 {
+		return
+	}
+	for i := range rows {
+		rows[i].Qty = rows[i].Qty.Round(int32(opt.QtyPrecision), opt.QtyRounding)
+	}
+	for i := range dups {
+		dups[i].Qty = dups[i].Qty.Round(int32(opt.QtyPrecision), opt.QtyRounding)
+	}
+}
+
+// qtyStatus — статус пары по дельте и допускам запроса. Допуски
+// независимы: достаточно уложиться в абсолютный или в относительный
+// (доля от большего из количеств).
+func qtyStatus(qa, qb, delta decimal.Decimal, opt model.Options) string {
+	if delta.IsZero() {
+		return StatusMatch
+	}
+	ad := delta.Abs()
+	if opt.QtyTolAbs.Sign() > 0 && ad.Cmp(opt.QtyTolAbs) <= 0 {
+		return StatusWithinTolerance
+	}
+	if opt.QtyTolRel.Sign() > 0 {
+		base := qa.Abs()
+		if qb.Abs().Cmp(base) > 0 {
+			base = qb.Abs()
+		}
+		if ad.Cmp(opt.QtyTolRel.Mul(base)) <= 0 {
+			return StatusWithinTolerance
+		}
+	}
+	if delta.Sign() > 0 {
+		return StatusSurplus
+	}
+	return StatusShortage
+}
+
+// statusStats — счётчики статусов в фиксированном порядке (нулевые тоже)
+func statusStats(rows []model.ResultRow) map[string]int {
+	out := make(map[string]int, len(statusOrder))
+	for _, s := range statusOrder {
+		out[s] = 0
+	}
+	for _, r := range rows {
+		out[r.Status]++
+	}
+	return out
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"testing"
+
+	"recon-service/internal/decimal"
+	"recon-service/internal/reconcile/model"
+)
+
+// Дубли складываются по точным количествам и лишь затем округляются:
+// 0.4+0.4+0.4 = 1.2 → 1 при QtyPrecision=0, а не 0+0+0.
+func TestAggregateRoundsSumNotRows(t *testing.T) {
+	row := func(q string) model.Row {
+		return model.Row{Name: "Болт М10", Qty: decimal.MustParse(q)}
+	}
+	opt := model.Options{
+		Normalization: true,
+		Lowercase:     true,
+		Threshold:     0.83,
+		QtyPrecision:  0,
+		QtyRounding:   decimal.HalfUp,
+	}
+	res := Run([]model.Row{row("0.4"), row("0.4"), row("0.4")}, []model.Row{row("1")}, opt)
+	if len(res.Rows) != 1 {
+		t.Fatalf("пар %d, ждём 1", len(res.Rows))
+	}
+	r := res.Rows[0]
+	if r.QtyA.String() != "1" || r.Status != StatusMatch {
+		t.Errorf("QtyA %s, статус %s; ждём 1 и %s", r.QtyA, r.Status, StatusMatch)
+	}
+	if len(res.Duplicates) != 1 || res.Duplicates[0].Qty.String() != "1" {
+		t.Errorf("дубли %+v, ждём одну группу с Qty 1", res.Duplicates)
+	}
+}
@@ -2,271 +2,331 @@ package service
 
 import (
 	"math"
-	"regexp"
-	"runtime"
-	"sort"
 	"strings"
-	"sync"
 
+	"recon-service/internal/decimal"
+	"recon-service/internal/reconcile/dictionary"
 	"recon-service/internal/reconcile/model"
 )
 
-// aggregate duplicates by key (prefer SKU; otherwise normalized name)
-func aggregate(rows []model.Row, opt model.Options) []model.Row {
-	agg := make(map[string]model.Row)
-	for _, r := range rows {
-		if r.NameNorm == "" {
-			r.NameNorm = normalize(r.Name, opt)
-		}
-		key := r.Sku
-		if key == "" {
-			key = r.NameNorm
-		}
-		if ex, ok := agg[key]; ok {
-			ex.Qty += r.Qty
-			agg[key] = ex
-		} else {
-			agg[key] = r
-		}
-	}
-	out := make([]model.Row, 0, len(agg))
-	for _, v := range agg {
-		out = append(out, v)
-	}
-	return out
-}
+// Проходы каскада в порядке приоритета
+const (
+	passCrosswalk = "crosswalk"
+	passSku       = "sku"
+	passExact     = "exact"
+	passFuzzy     = "fuzzy"
+)
+
+var passOrder = []string{passCrosswalk, passSku, passExact, passFuzzy}
 
-// Run — основная сверка. Строит индекс по B, матчит A→B, считает дельты.
-// Параллельная реализация: обрабатываем строки A в пуле воркеров, а
-// критические секции (выбор кандидата с учётом usedB) защищаем мьютексом.
+// Run — основная сверка. Строит индекс по B и матчит A→B каскадом
+// глобальных проходов: сначала SKU для всех строк A, затем точные
+// совпадения нормализованного имени и только потом fuzzy по остаткам.
+// Так fuzzy-пара для строки 3 не «крадёт» строку B, которая точно
+// совпала бы у строки 500.
 func Run(a, b []model.Row, opt model.Options) model.Result {
-	// 1) Нормализация
+	// 1) Нормализация (артикулы по правилам Mapping.Sku приводит вызывающий)
 	for i := range a {
 		if a[i].NameNorm == "" {
 			a[i].NameNorm = normalize(a[i].Name, opt)
 		}
+		if a[i].SkuNorm == "" {
+			a[i].SkuNorm = strings.TrimSpace(a[i].Sku)
+		}
 	}
 	for i := range b {
 		if b[i].NameNorm == "" {
 			b[i].NameNorm = normalize(b[i].Name, opt)
 		}
+		if b[i].SkuNorm == "" {
+			b[i].SkuNorm = strings.TrimSpace(b[i].Sku)
+		}
 	}
 
-	// 2) Агрегация дублей (SKU → иначе NameNorm)
-	a = aggregate(a, opt)
-	b = aggregate(b, opt)
+	// 2) Конфликты SKU/имён внутри таблиц — до слияния, пока варианты видны
+	skuNamesA, nameSkusA := keyConflicts(a, "A")
+	skuNamesB, nameSkusB := keyConflicts(b, "B")
+
+	// 3) Агрегация дублей (opt.Aggregate) по точным количествам, затем точность
+	inputA, inputB := len(a), len(b)
+	a, dupA := aggregate(a, opt, "A")
+	b, dupB := aggregate(b, opt, "B")
+	roundQty(a, dupA, opt)
+	roundQty(b, dupB, opt)
 
-	// 3) Индекс по B
+	// 4) Индекс по B (и таблица соответствия артикулов в его терминах)
 	idxB := buildIndexB(b)
+	idxB.cw = newCrosswalk(opt.Crosswalk)
+
+	// 5) Каскад проходов: глобальное назначение или жадный выбор
+	var res model.Result
+	if opt.Assignment == "optimal" {
+		res = runOptimal(a, b, idxB, opt)
+	} else {
+		res = runGreedy(a, b, idxB, opt)
+	}
+	res.DictionaryVersion = dictOf(opt).Version
+	res.Summary.InputA, res.Summary.InputB = inputA, inputB
+	res.Duplicates = append(dupA, dupB...)
+	res.Conflicts.SkuManyNames = append(skuNamesA, skuNamesB...)
+	res.Conflicts.NameManySkus = append(nameSkusA, nameSkusB...)
+	res.Crosswalk = crosswalkReport(opt.Crosswalk, a, b, res.Rows)
+	return res
+}
 
-	// 4) Учёт использованных записей B (ключи: "sku:<sku>", "name:<norm>")
-	usedB := make(map[string]bool, len(b))
-	var usedMu sync.Mutex
+// runGreedy — жадный режим: внутри каждого прохода строки A по порядку
+// берут лучшую свободную строку B (a и b уже нормализованы и агрегированы).
+func runGreedy(a, b []model.Row, idxB *Index, opt model.Options) model.Result {
+	// Учёт использованных строк B — по Row.ID, а не по ключам sku/name:
+	// строка B с тем же именем, но другим артикулом остаётся свободной
+	usedB := make([]bool, len(b))
+	matches := newMatches(len(a))
+	counts := make(map[string]int, len(passOrder))
+	sim := newScoreCache(newSimilarity(opt, idxB)) // fuzzy и chooseBest делят оценки
+
+	eps := tieEpsilon(opt)
+
+	take := func(i, j int, method string, score *float64, alts []alt) {
+		matches[i] = match{b: j, method: method, score: score, alts: alts}
+		if score != nil {
+			matches[i].metric = sim.Name()
+		}
+		usedB[j] = true
+		counts[method]++
+	}
 
-	type out struct {
-		row   *model.ResultRow
-		onlyA map[string]any
+	// (0) Таблица соответствия артикулов A → B — раньше прямого SKU
+	for i := range a {
+		if j, alts := chooseBest(idxB.crosswalkTargets(a[i].SkuNorm), b, a[i], usedB, sim, eps); j >= 0 {
+			take(i, j, passCrosswalk, nil, alts)
+		}
 	}
-	outs := make([]out, len(a))
-
-	// Кэши нормализаций для кандидатов (безопасны для гонок)
-	var normCache sync.Map  // candName -> candNorm
-	var unitsCache sync.Map // candNorm -> []string
-
-	// Воркер на одну строку A (по индексу i)
-	worker := func(i int) {
-		ar := a[i]
-		var matched *model.Row
-		var method string
-		var score *float64
-
-		// (1) Совпадение по SKU
-		if s := strings.TrimSpace(ar.Sku); s != "" {
-			usedMu.Lock()
-			if list, ok := idxB.bySku[s]; ok && len(list) > 0 {
-				if m := chooseBest(list, ar, usedB); m != nil {
-					matched = m
-					method = "sku"
-					markUsed(usedB, matched)
-				}
-			}
-			usedMu.Unlock()
+
+	// (1) Проход по SKU для оставшихся строк A
+	for i := range a {
+		s := a[i].SkuNorm
+		if matches[i].b >= 0 || s == "" {
+			continue
 		}
+		if j, alts := chooseBest(idxB.bySku[s], b, a[i], usedB, sim, eps); j >= 0 {
+			take(i, j, passSku, nil, alts)
+		}
+	}
 
-		// (2) Точное совпадение нормализованного имени
-		if matched == nil && strings.TrimSpace(ar.NameNorm) != "" {
-			usedMu.Lock()
-			if list, ok := idxB.byName[ar.NameNorm]; ok && len(list) > 0 {
-				if m := chooseBest(list, ar, usedB); m != nil {
-					matched = m
-					method = "exact"
-					markUsed(usedB, matched)
-				}
-			}
-			usedMu.Unlock()
+	// (2) Точные совпадения нормализованного имени по оставшимся
+	for i := range a {
+		if matches[i].b >= 0 || strings.TrimSpace(a[i].NameNorm) == "" {
+			continue
+		}
+		if j, alts := chooseBest(idxB.byName[a[i].NameNorm], b, a[i], usedB, sim, eps); j >= 0 {
+			take(i, j, passExact, nil, alts)
 		}
+	}
 
-		// (3) Fuzzy (тяжёлую часть — оценку схожести — считаем вне мьютекса)
-		if matched == nil && opt.EnableFuzzy && !opt.StrictAfterNorm && strings.TrimSpace(ar.NameNorm) != "" {
-			nuA := extractNumUnits(ar.NameNorm)
-
-			bestName := ""
-			best := -1.0
-
-			// 3.1 Кандидаты из инверт-индекса
-			for _, candName := range idxB.candidateNames(ar.NameNorm) {
-				// normalize(candidate) — с кэшем
-				var candNorm string
-				if v, ok := normCache.Load(candName); ok {
-					candNorm = v.(string)
-				} else {
-					candNorm = normalize(candName, opt)
-					normCache.Store(candName, candNorm)
-				}
-				// guard по единицам — с кэшем
-				var nuB []string
-				if v, ok := unitsCache.Load(candNorm); ok {
-					nuB = v.([]string)
-				} else {
-					nuB = extractNumUnits(candNorm)
-					unitsCache.Store(candNorm, nuB)
-				}
-				if !equalNumUnitsSoft(nuA, nuB) {
+	// (3) Fuzzy по остаткам: тяжёлую часть (ранжирование кандидатов)
+	// считаем параллельно, фиксацию — последовательно в порядке A
+	if opt.EnableFuzzy && !opt.StrictAfterNorm {
+		pending := make([]bool, len(a))
+		for i := range a {
+			pending[i] = matches[i].b < 0 && strings.TrimSpace(a[i].NameNorm) != ""
+		}
+		ranked := newFuzzyScorer(idxB, opt, sim).rankAll(a, pending)
+		for i := range a {
+			if !pending[i] {
+				continue
+			}
+			for k, c := range ranked[i] {
+				j, alts := chooseBest(idxB.byName[c.name], b, a[i], usedB, sim, eps)
+				if j < 0 {
 					continue
 				}
-
-				s := bestSimilarity(ar.NameNorm, candNorm)
-				if s > opt.Threshold && s > best {
-					best = s
-					bestName = candName
-				}
-			}
-
-			// 3.2 Fallback — полный проход по byName, если индекс пуст
-			if bestName == "" {
-				for candName := range idxB.byName {
-					var candNorm string
-					if v, ok := normCache.Load(candName); ok {
-						candNorm = v.(string)
-					} else {
-						candNorm = normalize(candName, opt)
-						normCache.Store(candName, candNorm)
+				// следующие по рангу имена в пределах eps — тоже равновероятны
+				for _, c2 := range ranked[i][k+1:] {
+					if c.score-c2.score > eps {
+						break
 					}
-					var nuB []string
-					if v, ok := unitsCache.Load(candNorm); ok {
-						nuB = v.([]string)
-					} else {
-						nuB = extractNumUnits(candNorm)
-						unitsCache.Store(candNorm, nuB)
-					}
-					if !equalNumUnitsSoft(nuA, nuB) {
-						continue
-					}
-					s := bestSimilarity(ar.NameNorm, candNorm)
-					if s > opt.Threshold && s > best {
-						best = s
-						bestName = candName
-					}
-				}
-			}
-
-			// 3.3 Фиксация результата
-			if bestName != "" {
-				usedMu.Lock()
-				if list, ok := idxB.byName[bestName]; ok && len(list) > 0 {
-					if m := chooseBest(list, ar, usedB); m != nil {
-						matched = m
-						method = "fuzzy"
-						score = &best
-						markUsed(usedB, matched)
+					for _, j2 := range idxB.byName[c2.name] {
+						if !usedB[j2] {
+							alts = append(alts, alt{b: j2, sim: c2.score})
+						}
 					}
 				}
-				usedMu.Unlock()
+				score := c.score
+				take(i, j, passFuzzy, &score, alts)
+				break
 			}
 		}
+	}
 
-		// 4) Запись результата, сохраняем порядок
-		if matched != nil {
-			outs[i].row = &model.ResultRow{
-				Name:   pick(ar.Name, matched.Name),
-				Sku:    pick(ar.Sku, matched.Sku),
-				QtyA:   ar.Qty,
-				QtyB:   matched.Qty,
-				Delta:  ar.Qty - matched.Qty,
-				Method: method,
-				Score:  score,
-			}
-		} else {
-			outs[i].onlyA = map[string]any{
-				"name": ar.Name,
-				"sku":  ar.Sku,
-				"qty":  ar.Qty,
+	pruneAlts(matches, usedB)
+	return assemble(a, b, opt, matches, usedB, counts, buildDetails(a, b, idxB, sim, opt, matches, usedB))
+}
+
+// pruneAlts убирает из альтернатив строки B, которые после выбора достались
+// другим строкам A: альтернативы запоминаются в момент выбора, а занятые
+// строки B дальше только прибавляются, так что достаточно пройти один раз
+// после всех проходов. Ambiguous считается по оставшимся (assemble).
+func pruneAlts(matches []match, usedB []bool) {
+	for i := range matches {
+		alts := matches[i].alts[:0]
+		for _, x := range matches[i].alts {
+			if !usedB[x.b] {
+				alts = append(alts, x)
 			}
 		}
+		if len(alts) == 0 {
+			alts = nil
+		}
+		matches[i].alts = alts
 	}
+}
 
-	// Пул воркеров по числу CPU
-	nw := runtime.NumCPU()
-	if nw < 1 {
-		nw = 1
-	}
+// match — выбранная для строки A строка B (b = Row.ID, -1 — нет пары)
+type match struct {
+	b      int
+	method string
+	score  *float64
+	metric string // метрика, давшая score
+	alts   []alt  // кандидаты в пределах eps от победителя, не занятые другими строками A
+}
 
-	type job struct{ i int }
-	jobs := make(chan job, nw*2)
-	var wg sync.WaitGroup
+// alt — кандидат, почти равный победителю (b = Row.ID)
+type alt struct {
+	b   int
+	sim float64
+}
 
-	for w := 0; w < nw; w++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for j := range jobs {
-				worker(j.i)
-			}
-		}()
+// tieEpsilon — граница «почти равных» оценок (opt.TieEpsilon, не меньше 0)
+func tieEpsilon(opt model.Options) float64 {
+	if opt.TieEpsilon < 0 {
+		return 0
 	}
+	return opt.TieEpsilon
+}
 
-	for i := range a {
-		jobs <- job{i: i}
+func newMatches(n int) []match {
+	m := make([]match, n)
+	for i := range m {
+		m[i].b = -1
 	}
-	close(jobs)
-	wg.Wait()
+	return m
+}
 
-	// Сборка результатов в стабильном порядке
+// assemble собирает результат в порядке A: пары, OnlyA, OnlyB и список
+// использованных строк B; det — explain и подсказки (см. suggest.go).
+func assemble(a, b []model.Row, opt model.Options, matches []match, usedB []bool, counts map[string]int, det details) model.Result {
 	rows := make([]model.ResultRow, 0, len(a))
-	onlyA := make([]map[string]any, 0)
-	for i := 0; i < len(a); i++ {
-		if outs[i].row != nil {
-			rows = append(rows, *outs[i].row)
+	onlyA := make([]model.UnmatchedRow, 0)
+	for i, ar := range a {
+		m := matches[i]
+		var cands []model.Candidate
+		if det.explain != nil {
+			cands = det.explain[i]
 		}
-		if outs[i].onlyA != nil {
-			onlyA = append(onlyA, outs[i].onlyA)
+		if m.b < 0 {
+			row := unmatched(ar)
+			row.Candidates = cands
+			if det.suggestA != nil {
+				row.Suggestions = det.suggestA[i]
+			}
+			onlyA = append(onlyA, row)
+			continue
 		}
+		br := b[m.b]
+		delta := ar.Qty.Sub(br.Qty)
+		rows = append(rows, model.ResultRow{
+			IDA:    ar.ID,
+			IDB:    br.ID,
+			Name:   pick(ar.Name, br.Name),
+			NameB:  br.Name,
+			KeyA:   ar.NameNorm,
+			KeyB:   br.NameNorm,
+			Sku:    pick(ar.Sku, br.Sku),
+			QtyA:   ar.Qty,
+			QtyB:   br.Qty,
+			Delta:  delta,
+			Status: qtyStatus(ar.Qty, br.Qty, delta, opt),
+			Method: m.method,
+			Score:  m.score,
+			Metric: m.metric,
+			SrcA:   ar.Src,
+			SrcB:   br.Src,
+			PartsA: ar.Parts,
+			PartsB: br.Parts,
+
+			Ambiguous:    len(m.alts) > 0,
+			Alternatives: alternatives(b, m),
+			Candidates:   cands,
+		})
 	}
 
-	// 5) OnlyB: всё из B, что не использовано
-	onlyB := make([]map[string]any, 0, len(b))
-	for _, br := range b {
-		usedBySku := false
-		usedByName := false
-		if s := strings.TrimSpace(br.Sku); s != "" {
-			usedBySku = usedB["sku:"+s]
-		}
-		if n := strings.TrimSpace(br.NameNorm); n != "" {
-			usedByName = usedB["name:"+n]
+	// OnlyB — ровно те строки B, что не вошли ни в одну пару
+	onlyB := make([]model.UnmatchedRow, 0, len(b))
+	used := make([]int, 0, len(b))
+	for j, br := range b {
+		if usedB[j] {
+			used = append(used, br.ID)
+			continue
 		}
-		if !(usedBySku || usedByName) {
-			onlyB = append(onlyB, map[string]any{
-				"name": br.Name,
-				"sku":  br.Sku,
-				"qty":  br.Qty,
-			})
+		row := unmatched(br)
+		if det.suggestB != nil {
+			row.Suggestions = det.suggestB[j]
 		}
+		onlyB = append(onlyB, row)
 	}
 
 	return model.Result{
-		Rows:  rows,
-		OnlyA: onlyA,
-		OnlyB: onlyB,
+		Rows:    rows,
+		OnlyA:   onlyA,
+		OnlyB:   onlyB,
+		Passes:  passStats(counts),
+		UsedB:   used,
+		Summary: summarize(a, b, opt, matches, usedB, rows, counts),
+		Conflicts: model.Conflicts{
+			SkuNameMismatch: skuNameMismatches(a, b, matches, opt),
+		},
+	}
+}
+
+// passStats — счётчики совпадений по проходам в порядке каскада
+func passStats(counts map[string]int) []model.PassStat {
+	out := make([]model.PassStat, 0, len(passOrder))
+	for _, p := range passOrder {
+		out = append(out, model.PassStat{Pass: p, Matched: counts[p]})
+	}
+	return out
+}
+
+// alternatives — почти равные кандидаты пары для ответа
+func alternatives(b []model.Row, m match) []model.Candidate {
+	if len(m.alts) == 0 {
+		return nil
+	}
+	out := make([]model.Candidate, 0, len(m.alts))
+	for _, x := range m.alts {
+		br := b[x.b]
+		out = append(out, model.Candidate{
+			IDB:   br.ID,
+			Name:  br.Name,
+			Sku:   br.Sku,
+			Qty:   br.Qty,
+			Pass:  m.method,
+			Score: x.sim,
+		})
+	}
+	return out
+}
+
+// unmatched — строка OnlyA / OnlyB без приложений (кандидаты, подсказки)
+func unmatched(r model.Row) model.UnmatchedRow {
+	return model.UnmatchedRow{
+		ID:    r.ID,
+		Name:  r.Name,
+		Key:   r.NameNorm,
+		Sku:   r.Sku,
+		Qty:   r.Qty,
+		Src:   r.Src,
+		Parts: r.Parts,
 	}
 }
 
@@ -277,44 +337,46 @@ func pick(a, b string) string {
 	return b
 }
 
-// Выбираем неиспользованного кандидата по smart-правилам:
-// 1) similarity desc
-// 2) при близком similarity (<= 0.02) — ненулевой qtyB лучше нулевого
+// Выбираем неиспользованного кандидата (ids — Row.ID строк из rows) по
+// smart-правилам:
+// 1) similarity desc (метрика запроса)
+// 2) при близком similarity (<= eps) — ненулевой qtyB лучше нулевого
 // 3) затем минимальная |QtyA-QtyB|
 // 4) стабильная ничья по индексу
-func chooseBest(cands []model.Row, ar model.Row, used map[string]bool) *model.Row {
+// Возвращает Row.ID победителя (или -1) и свободных кандидатов, чья
+// схожесть отличается от победителя не больше чем на eps: такой выбор
+// решён правилами 2–4, а не схожестью, и помечается как неоднозначный.
+func chooseBest(ids []int, rows []model.Row, ar model.Row, used []bool, metric Similarity, eps float64) (int, []alt) {
 	bestIdx := -1
 	bestSim := -1.0
 	bestNonZero := false
-	bestDelta := math.MaxFloat64
+	var bestDelta decimal.Decimal
+	sims := make([]float64, len(ids))
 
-	for i := range cands {
+	for i, id := range ids {
 		// пропускаем уже использованных
-		kSku := ""
-		if s := strings.TrimSpace(cands[i].Sku); s != "" {
-			kSku = "sku:" + s
-		}
-		kName := "name:" + cands[i].NameNorm
-		if (kSku != "" && used[kSku]) || used[kName] {
+		if used[id] {
 			continue
 		}
+		cand := rows[id]
 
-		sim := bestSimilarity(ar.NameNorm, cands[i].NameNorm)
-		nonZero := cands[i].Qty != 0
-		delta := math.Abs(ar.Qty - cands[i].Qty)
+		sim := metric.Score(ar.NameNorm, cand.NameNorm)
+		sims[i] = sim
+		nonZero := !cand.Qty.IsZero()
+		delta := ar.Qty.Sub(cand.Qty).Abs()
 
 		better := false
 		// 1) similarity
-		if sim > bestSim+0.02 {
+		if sim > bestSim+eps {
 			better = true
-		} else if math.Abs(sim-bestSim) <= 0.02 {
+		} else if math.Abs(sim-bestSim) <= eps {
 			// 2) ненулевой qtyB предпочтительнее
 			if nonZero != bestNonZero {
 				better = nonZero && !bestNonZero
-			} else if delta < bestDelta {
+			} else if c := delta.Cmp(bestDelta); bestIdx < 0 || c < 0 {
 				// 3) меньшая |Δ|
 				better = true
-			} else if delta == bestDelta && i < bestIdx {
+			} else if c == 0 && i < bestIdx {
 				// 4) стабильность
 				better = true
 			}
@@ -327,102 +389,42 @@ func chooseBest(cands []model.Row, ar model.Row, used map[string]bool) *model.Ro
 		}
 	}
 	if bestIdx == -1 {
-		return nil
+		return -1, nil
 	}
-	return &cands[bestIdx]
-}
 
-func markUsed(used map[string]bool, r *model.Row) {
-	if r == nil {
-		return
-	}
-	if s := strings.TrimSpace(r.Sku); s != "" {
-		used["sku:"+s] = true
-	}
-	if n := strings.TrimSpace(r.NameNorm); n != "" {
-		used["name:"+n] = true
+	var alts []alt
+	for i, id := range ids {
+		if i != bestIdx && !used[id] && math.Abs(sims[i]-bestSim) <= eps {
+			alts = append(alts, alt{b: id, sim: sims[i]})
+		}
 	}
+	return ids[bestIdx], alts
 }
 
-// --------- ВСПОМОГАТЕЛЬНОЕ: нормализация и «число+единица» ---------
+// --------- ВСПОМОГАТЕЛЬНОЕ: нормализация (см. normalize.go, units.go) ---------
 
-// normalize — совместимая обёртка вокруг твоего NameKey (из normalize.go)
-func normalize(s string, _ model.Options) string {
-	return NameKey(s) // см. normalize.go
+// normalize строит ключ по флагам нормализации из опций (см. normalize.go)
+func normalize(s string, opt model.Options) string {
+	return pipelineFromOptions(opt).Key(s)
 }
 
-// extractNumUnits вытаскивает из нормализованной строки размер "1200x800"
-// и шаблоны вида "<число><единица>" (л, кг, г, мл, мм, см, м, pcs...).
-var (
-	reDimToken   = regexp.MustCompile(`^\d{2,5}x\d{2,5}$`)
-	reNumUnit    = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)(шт|л|кг|г|гр|мл|мм|см|м|pcs|pc|l|kg|g|ml|mm|cm|m)$`)
-	unitCanonMap = map[string]string{
-		"шт": "pcs", "pcs": "pcs", "pc": "pcs",
-		"л": "l", "l": "l",
-		"кг": "kg", "kg": "kg",
-		"г": "g", "гр": "g", "g": "g",
-		"мл": "ml", "ml": "ml",
-		"мм": "mm", "mm": "mm",
-		"см": "cm", "cm": "cm",
-		"м": "m", "m": "m",
+func pipelineFromOptions(opt model.Options) pipeline {
+	return pipeline{
+		Normalization: opt.Normalization,
+		TokenSort:     opt.TokenSort,
+		StripUnits:    opt.StripUnits,
+		Unify:         opt.Unify,
+		Lowercase:     opt.Lowercase,
+		Stemming:      opt.Stemming,
+		Dims:          dimOptions{Sort: opt.DimSort, Third: opt.Dim3D},
+		Dict:          dictOf(opt),
 	}
-)
-
-func extractNumUnits(norm string) []string {
-	if norm == "" {
-		return nil
-	}
-	toks := strings.Fields(norm)
-	out := make([]string, 0, 4)
-	for _, t := range toks {
-		if reDimToken.MatchString(t) {
-			out = append(out, t)
-			continue
-		}
-		if m := reNumUnit.FindStringSubmatch(t); len(m) == 3 {
-			num := strings.ReplaceAll(m[1], ",", ".")
-			unit := unitCanonMap[m[2]]
-			if unit != "" {
-				out = append(out, num+unit)
-			}
-		}
-	}
-	return out
 }
 
-// сравнение отсортированных мультимножеств "число+единица"
-func equalNumUnitsSoft(a, b []string) bool {
-	// канонизируем десятичный разделитель
-	for i := range a {
-		a[i] = strings.ReplaceAll(a[i], ",", ".")
-	}
-	for i := range b {
-		b[i] = strings.ReplaceAll(b[i], ",", ".")
-	}
-
-	ac, bc := append([]string(nil), a...), append([]string(nil), b...)
-	sort.Strings(ac)
-	sort.Strings(bc)
-	if len(ac) > len(bc)+1 {
-		return false
-	}
-	i, j, miss := 0, 0, 0
-	for i < len(ac) && j < len(bc) {
-		if ac[i] == bc[j] {
-			i++
-			j++
-			continue
-		}
-		miss++
-		if miss > 1 {
-			return false
-		}
-		if ac[i] < bc[j] {
-			i++
-		} else {
-			j++
-		}
+// dictOf — словарь запроса; без явного словаря — встроенный
+func dictOf(opt model.Options) *dictionary.Dictionary {
+	if opt.Dict != nil {
+		return opt.Dict
 	}
-	miss += (len(ac) - i) + (len(bc) - j)
-	return miss <= 1
+	return dictionary.Builtin()
 }
@@ -0,0 +1,450 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"recon-service/internal/reconcile/model"
+)
+
+// --------- МЕТРИКИ СХОЖЕСТИ (opt.Metric) ---------
+
+// Similarity — метрика схожести двух нормализованных имён в [0..1].
+// Реализации только читают общие данные и безопасны для параллельного
+// использования (fuzzy ранжирует кандидатов в пуле воркеров).
+type Similarity interface {
+	Name() string
+	Score(a, b string) float64
+}
+
+// boundedSimilarity — метрика с ранним выходом: если оценка заведомо не
+// выше min, счёт обрывается и возвращается оценка снизу (< min).
+type boundedSimilarity interface {
+	ScoreMin(a, b string, min float64) float64
+}
+
+// scoreMin — оценка с ранним выходом, если метрика его поддерживает
+func scoreMin(s Similarity, a, b string, min float64) float64 {
+	if bs, ok := s.(boundedSimilarity); ok {
+		return bs.ScoreMin(a, b, min)
+	}
+	return s.Score(a, b)
+}
+
+// Имена метрик (значения opt.Metric и ResultRow.Metric)
+const (
+	MetricDamerau     = "damerau"      // max(DL, DL по отсортированным токенам) — прежнее поведение
+	MetricJaroWinkler = "jaro_winkler" // устойчива к хвостам, ценит общий префикс
+	MetricTokenSet    = "token_set"    // лишние слова в одном из имён не штрафуются
+	MetricTFIDF       = "tfidf"        // косинус TF-IDF по триграммам B
+	MetricIDF         = "idf"          // токены с весом IDF по B: редкие коды и размеры важнее общих слов
+	MetricWeighted    = "weighted"     // взвешенная сумма метрик (opt.MetricWeights)
+)
+
+// defaultWeights — веса для metric=weighted, если opt.MetricWeights пуст
+var defaultWeights = map[string]float64{
+	MetricDamerau:     0.4,
+	MetricTokenSet:    0.4,
+	MetricJaroWinkler: 0.2,
+}
+
+// Metrics — поддерживаемые метрики (для валидации и подсказок)
+func Metrics() []string {
+	return []string{MetricDamerau, MetricJaroWinkler, MetricTokenSet, MetricTFIDF, MetricIDF, MetricWeighted}
+}
+
+// ValidMetric — известна ли метрика; пустое имя — метрика по умолчанию
+func ValidMetric(name string) bool {
+	if name == "" {
+		return true
+	}
+	for _, m := range Metrics() {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// trigramCutoff — порог для отсечки candidateNames: граница по триграммам
+// выведена для Дамерау-Левенштейна, у прочих метрик её нет, и кандидатов
+// ограничивает только top-K.
+func trigramCutoff(opt model.Options, threshold float64) float64 {
+	if opt.Metric == "" || opt.Metric == MetricDamerau {
+		return threshold
+	}
+	return 0
+}
+
+// newSimilarity собирает метрику запроса; idx нужен метрикам,
+// зависящим от корпуса B (tfidf, idf).
+func newSimilarity(opt model.Options, idx *Index) Similarity {
+	switch opt.Metric {
+	case MetricWeighted:
+		weights := opt.MetricWeights
+		if len(weights) == 0 {
+			weights = defaultWeights
+		}
+		names := make([]string, 0, len(weights))
+		for n := range weights {
+			names = append(names, n)
+		}
+		sort.Strings(names) // порядок суммирования не должен плавать
+		w := weightedSimilarity{}
+		for _, n := range names {
+			if n == MetricWeighted || weights[n] <= 0 {
+				continue
+			}
+			w.parts = append(w.parts, weightedPart{
+				metric: newSimilarity(model.Options{Metric: n}, idx),
+				weight: weights[n],
+			})
+		}
+		if len(w.parts) == 0 {
+			return damerauSimilarity{}
+		}
+		return w
+	default:
+		return baseSimilarity(opt.Metric, idx)
+	}
+}
+
+func baseSimilarity(name string, idx *Index) Similarity {
+	switch name {
+	case MetricJaroWinkler:
+		return jaroWinklerSimilarity{}
+	case MetricTokenSet:
+		return tokenSetSimilarity{}
+	case MetricTFIDF:
+		return newTFIDFSimilarity(idx)
+	case MetricIDF:
+		return newIDFSimilarity(idx)
+	default:
+		return damerauSimilarity{}
+	}
+}
+
+// ---------- damerau ----------
+
+type damerauSimilarity struct{}
+
+func (damerauSimilarity) Name() string              { return MetricDamerau }
+func (damerauSimilarity) Score(a, b string) float64 { return bestSimilarity(a, b) }
+func (damerauSimilarity) ScoreMin(a, b string, min float64) float64 {
+	return bestSimilarityMin(a, b, min)
+}
+
+// ---------- jaro_winkler ----------
+
+type jaroWinklerSimilarity struct{}
+
+func (jaroWinklerSimilarity) Name() string { return MetricJaroWinkler }
+
+func (jaroWinklerSimilarity) Score(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+	win := max(len(ra), len(rb))/2 - 1
+	if win < 0 {
+		win = 0
+	}
+	ma := make([]bool, len(ra))
+	mb := make([]bool, len(rb))
+	m := 0
+	for i := range ra {
+		lo, hi := i-win, i+win+1
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(rb) {
+			hi = len(rb)
+		}
+		for j := lo; j < hi; j++ {
+			if !mb[j] && ra[i] == rb[j] {
+				ma[i], mb[j] = true, true
+				m++
+				break
+			}
+		}
+	}
+	if m == 0 {
+		return 0
+	}
+	// полутранспозиции
+	t, k := 0, 0
+	for i := range ra {
+		if !ma[i] {
+			continue
+		}
+		for !mb[k] {
+			k++
+		}
+		if ra[i] != rb[k] {
+			t++
+		}
+		k++
+	}
+	fm := float64(m)
+	jaro := (fm/float64(len(ra)) + fm/float64(len(rb)) + (fm-float64(t)/2)/fm) / 3
+
+	// бонус за общий префикс (до 4 символов)
+	l := 0
+	for l < 4 && l < len(ra) && l < len(rb) && ra[l] == rb[l] {
+		l++
+	}
+	return jaro + float64(l)*0.1*(1-jaro)
+}
+
+// ---------- token_set ----------
+
+// tokenSetSimilarity — token set ratio: сравниваем общую часть токенов с
+// общей частью + остатком каждой стороны и берём лучшее. "поддон
+// деревянный 1200x800" и "поддон 1200x800" получают высокую оценку.
+type tokenSetSimilarity struct{}
+
+func (tokenSetSimilarity) Name() string { return MetricTokenSet }
+
+func (tokenSetSimilarity) Score(a, b string) float64 {
+	ta, tb := tokenBag(a), tokenBag(b)
+	var inter, onlyA, onlyB []string
+	for t := range ta {
+		if _, ok := tb[t]; ok {
+			inter = append(inter, t)
+		} else {
+			onlyA = append(onlyA, t)
+		}
+	}
+	for t := range tb {
+		if _, ok := ta[t]; !ok {
+			onlyB = append(onlyB, t)
+		}
+	}
+	sort.Strings(inter)
+	sort.Strings(onlyA)
+	sort.Strings(onlyB)
+
+	t0 := strings.Join(inter, " ")
+	t1 := strings.TrimSpace(t0 + " " + strings.Join(onlyA, " "))
+	t2 := strings.TrimSpace(t0 + " " + strings.Join(onlyB, " "))
+	best := similarity(t1, t2)
+	if t0 != "" {
+		best = math.Max(best, math.Max(similarity(t0, t1), similarity(t0, t2)))
+	}
+	return best
+}
+
+func tokenBag(s string) map[string]struct{} {
+	m := make(map[string]struct{})
+	for _, t := range strings.Fields(s) {
+		m[t] = struct{}{}
+	}
+	return m
+}
+
+// ---------- tfidf ----------
+
+// tfidfSimilarity — косинус векторов триграмм с весами IDF. Частоты берём
+// из триграммного индекса B: триграммы, встречающиеся почти во всех
+// именах ("под", "дон"), почти ничего не весят.
+type tfidfSimilarity struct {
+	idx *Index
+	n   float64 // число различных имён B
+}
+
+func newTFIDFSimilarity(idx *Index) tfidfSimilarity {
+	return tfidfSimilarity{idx: idx, n: float64(len(idx.byName))}
+}
+
+func (tfidfSimilarity) Name() string { return MetricTFIDF }
+
+func (s tfidfSimilarity) idf(g string) float64 {
+	df := float64(len(s.idx.inv[g]))
+	return math.Log((s.n+1)/(df+1)) + 1
+}
+
+func (s tfidfSimilarity) Score(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ga, gb := trigramSet(a), trigramSet(b)
+	if len(ga) == 0 || len(gb) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for g := range ga {
+		w := s.idf(g)
+		na += w * w
+		if _, ok := gb[g]; ok {
+			dot += w * w
+		}
+	}
+	for g := range gb {
+		w := s.idf(g)
+		nb += w * w
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// ---------- idf ----------
+
+// idfTokenMin — с какой схожести два токена считаются «почти одинаковыми»
+// (опечатка, падеж): "деревяный" ~ "деревянный"
+const idfTokenMin = 0.8
+
+// idfSimilarity — взвешенный Dice по токенам: каждый токен весит по IDF
+// среди имён B. Если половина каталога — «поддон деревянный …», общие
+// слова почти ничего не дают, а совпадение кода модели или габарита —
+// почти всё. Мягко (DL ≥ idfTokenMin) сопоставляются только чисто
+// буквенные токены; токены с цифрами (din933, м10, 1200x800) — только
+// точно: din933 и din934 — разные товары, а не опечатка.
+type idfSimilarity struct {
+	idx *Index
+	n   float64 // число различных имён B
+}
+
+func newIDFSimilarity(idx *Index) idfSimilarity {
+	return idfSimilarity{idx: idx, n: float64(len(idx.byName))}
+}
+
+func (idfSimilarity) Name() string { return MetricIDF }
+
+// weight — IDF токена; токен, которого нет в B, весит максимально
+func (s idfSimilarity) weight(t string) float64 {
+	return math.Log((s.n+1)/(float64(s.idx.df[t])+1)) + 1
+}
+
+func (s idfSimilarity) Score(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ta, tb := strings.Fields(a), strings.Fields(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	wb := make([]float64, len(tb))
+	var total float64
+	for j, t := range tb {
+		wb[j] = s.weight(t)
+		total += wb[j]
+	}
+
+	used := make([]bool, len(tb))
+	var common float64
+	for _, t := range ta {
+		w := s.weight(t)
+		total += w
+
+		// лучший свободный токен B (точное совпадение — сразу)
+		best, bestSim := -1, 0.0
+		soft := isAlphaToken(t)
+		for j, u := range tb {
+			if used[j] {
+				continue
+			}
+			if u == t {
+				best, bestSim = j, 1
+				break
+			}
+			if !soft || !isAlphaToken(u) {
+				continue
+			}
+			if sim := similarityMin(t, u, idfTokenMin); sim >= idfTokenMin && sim > bestSim {
+				best, bestSim = j, sim
+			}
+		}
+		if best >= 0 {
+			used[best] = true
+			common += bestSim * (w + wb[best])
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return common / total
+}
+
+// ---------- weighted ----------
+
+type weightedPart struct {
+	metric Similarity
+	weight float64
+}
+
+// weightedSimilarity — нормированная взвешенная сумма метрик
+type weightedSimilarity struct {
+	parts []weightedPart
+}
+
+func (weightedSimilarity) Name() string { return MetricWeighted }
+
+func (w weightedSimilarity) Score(a, b string) float64 {
+	var sum, total float64
+	for _, p := range w.parts {
+		sum += p.weight * p.metric.Score(a, b)
+		total += p.weight
+	}
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
+
+// ---------- кэш оценок пар ----------
+
+type pairKey struct{ a, b string }
+
+// scoreCache запоминает точные оценки пар, чтобы chooseBest не пересчитывал
+// то, что уже посчитал fuzzy-проход. Оценки с ранним выходом (ниже min)
+// не кэшируются — они лишь оценка снизу; это же держит кэш маленьким.
+type scoreCache struct {
+	inner Similarity
+	m     *sync.Map // pairKey -> float64
+}
+
+func newScoreCache(inner Similarity) scoreCache {
+	return scoreCache{inner: inner, m: new(sync.Map)}
+}
+
+func (c scoreCache) Name() string { return c.inner.Name() }
+
+func (c scoreCache) Score(a, b string) float64 {
+	k := pairKey{a, b}
+	if v, ok := c.m.Load(k); ok {
+		return v.(float64)
+	}
+	s := c.inner.Score(a, b)
+	c.m.Store(k, s)
+	return s
+}
+
+func (c scoreCache) ScoreMin(a, b string, min float64) float64 {
+	k := pairKey{a, b}
+	if v, ok := c.m.Load(k); ok {
+		return v.(float64)
+	}
+	s := scoreMin(c.inner, a, b, min)
+	if s > min {
+		c.m.Store(k, s)
+	}
+	return s
+}
+
+// isAlphaToken — токен только из букв (без цифр и знаков)
+func isAlphaToken(t string) bool {
+	for _, r := range t {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return t != ""
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"testing"
+
+	"recon-service/internal/reconcile/model"
+)
+
+// Коды моделей с цифрами у idf сравниваются только точно: din933 и din934 —
+// разные товары, и схожесть не должна дотягивать до порога.
+func TestIDFDigitTokensMatchExactly(t *testing.T) {
+	var b []model.Row
+	for _, n := range []string{
+		"болт din933 м10", "болт din931 м10", "гайка din934 м10",
+		"шайба din125 м10", "болт din933 м12", "гайка din985 м10",
+	} {
+		b = append(b, model.Row{Name: n, NameNorm: n})
+	}
+	sim := newSimilarity(model.Options{Metric: MetricIDF}, buildIndexB(b))
+
+	if s := sim.Score("болт din933 м10", "болт din934 м10"); s >= 0.83 {
+		t.Errorf("din933 vs din934: %.3f, ждём ниже порога 0.83", s)
+	}
+	// буквенные токены по-прежнему прощают опечатку
+	if s := sim.Score("болт din933 м10", "болтт din933 м10"); s < 0.83 {
+		t.Errorf("болт vs болтт: %.3f, ждём не ниже 0.83", s)
+	}
+}
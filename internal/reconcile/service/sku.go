@@ -0,0 +1,101 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"recon-service/internal/reconcile/model"
+)
+
+// --------- НОРМАЛИЗАЦИЯ АРТИКУЛОВ (model.SkuRules) ---------
+
+// latinOf — обратная таблица homoglyphs: кириллический двойник → латиница.
+// Для артикулов направление обратное unifyToken: коды почти всегда латиницей.
+var latinOf = func() map[rune]rune {
+	m := make(map[rune]rune, len(homoglyphs))
+	for lat, cyr := range homoglyphs {
+		m[cyr] = lat
+	}
+	return m
+}()
+
+// SkuNormalizer — скомпилированные SkuRules одной стороны
+type SkuNormalizer struct {
+	rules  model.SkuRules
+	prefix *regexp.Regexp
+	suffix *regexp.Regexp
+}
+
+// NewSkuNormalizer компилирует правила; ошибка — некорректная регулярка.
+// При Fold регулярки нечувствительны к регистру: они срабатывают по
+// исходному тексту, и "ART" должен снимать и "art 123".
+func NewSkuNormalizer(rules model.SkuRules) (*SkuNormalizer, error) {
+	n := &SkuNormalizer{rules: rules}
+	flags := ""
+	if rules.Fold {
+		flags = "(?i)"
+	}
+	var err error
+	if p := strings.TrimSpace(rules.Prefix); p != "" {
+		if n.prefix, err = regexp.Compile(flags + `^(?:` + p + `)`); err != nil {
+			return nil, fmt.Errorf("sku prefix: %w", err)
+		}
+	}
+	if s := strings.TrimSpace(rules.Suffix); s != "" {
+		if n.suffix, err = regexp.Compile(flags + `(?:` + s + `)$`); err != nil {
+			return nil, fmt.Errorf("sku suffix: %w", err)
+		}
+	}
+	return n, nil
+}
+
+// Normalize приводит артикул по правилам (в порядке полей SkuRules).
+// Если правила съели артикул целиком, остаётся исходный после TrimSpace:
+// пустой SkuNorm означал бы «артикула нет».
+func (n *SkuNormalizer) Normalize(sku string) string {
+	raw := strings.TrimSpace(sku)
+	if n == nil || raw == "" {
+		return raw
+	}
+	s := raw
+	if n.prefix != nil {
+		s = n.prefix.ReplaceAllString(s, "")
+	}
+	if n.suffix != nil {
+		s = n.suffix.ReplaceAllString(s, "")
+	}
+	s = strings.TrimSpace(s) // "art 123" → "123", иначе TrimZeros упрётся в пробел
+	if n.rules.Fold {
+		s = strings.ToUpper(s)
+	}
+	if n.rules.Homoglyphs {
+		s = strings.Map(func(r rune) rune {
+			if l, ok := latinOf[r]; ok {
+				return l
+			}
+			return r
+		}, s)
+	}
+	if n.rules.StripSeps {
+		s = strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) || strings.ContainsRune("-_./\\–—", r) {
+				return -1
+			}
+			return r
+		}, s)
+	}
+	if n.rules.TrimZeros {
+		if t := strings.TrimLeft(s, "0"); t != "" {
+			s = t
+		} else if s != "" {
+			s = "0"
+		}
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return raw
+	}
+	return s
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"testing"
+
+	"recon-service/internal/reconcile/model"
+)
+
+// Prefix/Suffix срабатывают по исходному тексту; при Fold — без учёта регистра.
+func TestSkuNormalizerFoldAppliesToRegexes(t *testing.T) {
+	n, err := NewSkuNormalizer(model.SkuRules{Prefix: "ART|АРТ", Suffix: "-(OLD|NEW)", Fold: true, TrimZeros: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for in, want := range map[string]string{
+		"art 123":     "123",
+		"арт 00123":   "123",
+		"Art 123-old": "123",
+		"123-New":     "123",
+	} {
+		if got := n.Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, ждём %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+// --------- СТЕММИНГ: Snowball (Russian) и Porter2 (English) ---------
+//
+// "поддоны", "поддона", "поддон" → "поддон". Стеммер применяется к
+// словарным токенам ключа; числа, количества и габариты не трогаем.
+
+// stemToken выбирает стеммер по алфавиту токена; смешанные токены
+// (модели, коды "м8x40") возвращаются как есть.
+func stemToken(t string) string {
+	cyr, lat := false, false
+	for _, r := range t {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyr = true
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			lat = true
+		default:
+			return t
+		}
+	}
+	switch {
+	case cyr && !lat:
+		return stemRu(t)
+	case lat && !cyr:
+		return stemEn(t)
+	default:
+		return t
+	}
+}
+
+// ---------- Russian ----------
+
+func isVowelRu(r rune) bool {
+	switch r {
+	case 'а', 'е', 'и', 'о', 'у', 'ы', 'э', 'ю', 'я':
+		return true
+	}
+	return false
+}
+
+var (
+	ruPerfectiveGerund1 = []string{"вшись", "вши", "в"}
+	ruPerfectiveGerund2 = []string{"ывшись", "ившись", "ывши", "ивши", "ыв", "ив"}
+	ruAdjective         = []string{
+		"ими", "ыми", "его", "ого", "ему", "ому",
+		"ее", "ие", "ые", "ое", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом",
+		"их", "ых", "ую", "юю", "ая", "яя", "ою", "ею",
+	}
+	ruParticiple1 = []string{"ем", "нн", "вш", "ющ", "щ"}
+	ruParticiple2 = []string{"ивш", "ывш", "ующ"}
+	ruReflexive   = []string{"ся", "сь"}
+	ruVerb1       = []string{"ете", "йте", "ешь", "нно", "ла", "на", "ли", "ем", "ло", "но", "ет", "ют", "ны", "ть", "й", "л", "н"}
+	ruVerb2       = []string{
+		"ейте", "уйте", "ила", "ыла", "ена", "ите", "или", "ыли", "ило", "ыло", "ено",
+		"ует", "уют", "ены", "ить", "ыть", "ишь", "ей", "уй", "ил", "ыл", "им", "ым",
+		"ен", "ят", "ит", "ыт", "ую", "ю",
+	}
+	ruNoun = []string{
+		"иями", "ями", "ами", "ией", "иям", "ием", "иях",
+		"ев", "ов", "ие", "ье", "еи", "ии", "ей", "ой", "ий", "ям", "ем", "ам", "ом", "ах", "ях", "ию", "ью", "ия", "ья",
+		"а", "е", "и", "й", "о", "у", "ы", "ь", "ю", "я",
+	}
+	ruSuperlative  = []string{"ейше", "ейш"}
+	ruDerivational = []string{"ость", "ост"}
+)
+
+// ruSuffix ищет самое длинное окончание из list, целиком лежащее в
+// w[from:]; для групп «после а/я» предыдущая буква тоже должна быть в
+// w[from:]. Возвращает длину окончания (0 — не найдено).
+func ruSuffix(w []rune, from int, list []string, afterAYa bool) int {
+	best := 0
+	for _, suf := range list {
+		sr := []rune(suf)
+		n := len(sr)
+		if n <= best || len(w)-n < from {
+			continue
+		}
+		if string(w[len(w)-n:]) != suf {
+			continue
+		}
+		if afterAYa {
+			p := len(w) - n - 1
+			if p < from || (w[p] != 'а' && w[p] != 'я') {
+				continue
+			}
+		}
+		best = n
+	}
+	return best
+}
+
+// ruEnding — самое длинное окончание из двух групп (первая — после а/я)
+func ruEnding(w []rune, from int, g1, g2 []string) int {
+	n1 := ruSuffix(w, from, g1, true)
+	n2 := ruSuffix(w, from, g2, false)
+	if n2 > n1 {
+		return n2
+	}
+	return n1
+}
+
+func stemRu(word string) string {
+	w := []rune(strings.ReplaceAll(strings.ToLower(word), "ё", "е"))
+
+	// RV — после первой гласной; R1/R2 — стандартные области Snowball
+	rv := len(w)
+	for i, r := range w {
+		if isVowelRu(r) {
+			rv = i + 1
+			break
+		}
+	}
+	region := func(start int) int {
+		for i := start + 1; i < len(w); i++ {
+			if !isVowelRu(w[i]) && isVowelRu(w[i-1]) {
+				return i + 1
+			}
+		}
+		return len(w)
+	}
+	r1 := region(0)
+	r2 := region(r1)
+	if r1 == len(w) {
+		r2 = len(w)
+	}
+
+	cut := func(n int) { w = w[:len(w)-n] }
+
+	// Шаг 1
+	if n := ruEnding(w, rv, ruPerfectiveGerund1, ruPerfectiveGerund2); n > 0 {
+		cut(n)
+	} else {
+		if n := ruSuffix(w, rv, ruReflexive, false); n > 0 {
+			cut(n)
+		}
+		if n := ruSuffix(w, rv, ruAdjective, false); n > 0 {
+			cut(n)
+			if p := ruEnding(w, rv, ruParticiple1, ruParticiple2); p > 0 {
+				cut(p)
+			}
+		} else if n := ruEnding(w, rv, ruVerb1, ruVerb2); n > 0 {
+			cut(n)
+		} else if n := ruSuffix(w, rv, ruNoun, false); n > 0 {
+			cut(n)
+		}
+	}
+
+	// Шаг 2
+	if len(w) > rv && w[len(w)-1] == 'и' {
+		cut(1)
+	}
+
+	// Шаг 3
+	if n := ruSuffix(w, r2, ruDerivational, false); n > 0 {
+		cut(n)
+	}
+
+	// Шаг 4
+	undouble := func() bool {
+		if len(w)-2 >= rv && w[len(w)-1] == 'н' && w[len(w)-2] == 'н' {
+			cut(1)
+			return true
+		}
+		return false
+	}
+	if !undouble() {
+		if n := ruSuffix(w, rv, ruSuperlative, false); n > 0 {
+			cut(n)
+			undouble()
+		} else if len(w) > rv && w[len(w)-1] == 'ь' {
+			cut(1)
+		}
+	}
+	return string(w)
+}
+
+// ---------- English (Porter2) ----------
+
+func isVowelEn(b byte) bool {
+	switch b {
+	case 'a', 'e', 'i', 'o', 'u', 'y':
+		return true
+	}
+	return false
+}
+
+type enWord struct {
+	b      []byte
+	r1, r2 int
+}
+
+func (w *enWord) has(suf string) bool    { return strings.HasSuffix(string(w.b), suf) }
+func (w *enWord) stemLen(suf string) int { return len(w.b) - len(suf) }
+func (w *enWord) replace(suf, rep string) {
+	w.b = append(w.b[:len(w.b)-len(suf)], rep...)
+}
+
+// vowelBefore — есть ли гласная в b[:end]
+func (w *enWord) vowelBefore(end int) bool {
+	for i := 0; i < end; i++ {
+		if isVowelEn(w.b[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// shortSyllableAt — короткий слог, заканчивающийся на позиции end-1
+func (w *enWord) shortSyllableAt(end int) bool {
+	b := w.b[:end]
+	n := len(b)
+	if n == 2 {
+		return isVowelEn(b[0]) && !isVowelEn(b[1])
+	}
+	if n >= 3 {
+		c := b[n-1]
+		return !isVowelEn(b[n-3]) && isVowelEn(b[n-2]) && !isVowelEn(c) &&
+			c != 'w' && c != 'x' && c != 'Y'
+	}
+	return false
+}
+
+func (w *enWord) isShort() bool {
+	return w.r1 >= len(w.b) && w.shortSyllableAt(len(w.b))
+}
+
+func stemEn(word string) string {
+	s := strings.ToLower(word)
+	if len(s) <= 2 {
+		return s
+	}
+	w := &enWord{b: []byte(s)}
+	if w.b[0] == 'y' {
+		w.b[0] = 'Y'
+	}
+	for i := 1; i < len(w.b); i++ {
+		if w.b[i] == 'y' && isVowelEn(w.b[i-1]) {
+			w.b[i] = 'Y'
+		}
+	}
+
+	region := func(start int) int {
+		for i := start + 1; i < len(w.b); i++ {
+			if !isVowelEn(w.b[i]) && isVowelEn(w.b[i-1]) {
+				return i + 1
+			}
+		}
+		return len(w.b)
+	}
+	w.r1 = region(0)
+	for _, p := range []string{"gener", "commun", "arsen"} {
+		if strings.HasPrefix(s, p) {
+			w.r1 = len(p)
+		}
+	}
+	w.r2 = region(w.r1)
+	if w.r1 >= len(w.b) {
+		w.r2 = len(w.b)
+	}
+
+	// Step 1a
+	switch {
+	case w.has("sses"):
+		w.replace("sses", "ss")
+	case w.has("ied"), w.has("ies"):
+		if w.stemLen("ies") > 1 {
+			w.replace("ies", "i")
+		} else {
+			w.replace("ies", "ie")
+		}
+	case w.has("us"), w.has("ss"):
+	case w.has("s"):
+		if w.vowelBefore(len(w.b) - 2) {
+			w.replace("s", "")
+		}
+	}
+
+	// Step 1b
+	step1bSuffix := ""
+	for _, suf := range []string{"eedly", "ingly", "edly", "eed", "ing", "ed"} {
+		if w.has(suf) {
+			step1bSuffix = suf
+			break
+		}
+	}
+	switch step1bSuffix {
+	case "eedly", "eed":
+		if w.stemLen(step1bSuffix) >= w.r1 {
+			w.replace(step1bSuffix, "ee")
+		}
+	case "ingly", "edly", "ing", "ed":
+		if w.vowelBefore(w.stemLen(step1bSuffix)) {
+			w.replace(step1bSuffix, "")
+			switch {
+			case w.has("at"), w.has("bl"), w.has("iz"):
+				w.b = append(w.b, 'e')
+			case len(w.b) >= 2 && w.b[len(w.b)-1] == w.b[len(w.b)-2] &&
+				strings.IndexByte("bdfgmnprt", w.b[len(w.b)-1]) >= 0:
+				w.b = w.b[:len(w.b)-1]
+			case w.isShort():
+				w.b = append(w.b, 'e')
+			}
+		}
+	}
+
+	// Step 1c
+	if n := len(w.b); n > 2 && (w.b[n-1] == 'y' || w.b[n-1] == 'Y') && !isVowelEn(w.b[n-2]) {
+		w.b[n-1] = 'i'
+	}
+
+	// Step 2
+	step2 := []struct{ suf, rep string }{
+		{"ization", "ize"}, {"ational", "ate"}, {"fulness", "ful"}, {"ousness", "ous"},
+		{"iveness", "ive"}, {"tional", "tion"}, {"biliti", "ble"}, {"lessli", "less"},
+		{"entli", "ent"}, {"ation", "ate"}, {"alism", "al"}, {"aliti", "al"},
+		{"ousli", "ous"}, {"iviti", "ive"}, {"fulli", "ful"}, {"enci", "ence"},
+		{"anci", "ance"}, {"abli", "able"}, {"izer", "ize"}, {"ator", "ate"},
+		{"alli", "al"}, {"bli", "ble"}, {"ogi", "og"}, {"li", ""},
+	}
+	for _, r := range step2 {
+		if !w.has(r.suf) {
+			continue
+		}
+		if w.stemLen(r.suf) >= w.r1 {
+			switch r.suf {
+			case "ogi":
+				if w.stemLen(r.suf) > 0 && w.b[w.stemLen(r.suf)-1] == 'l' {
+					w.replace(r.suf, r.rep)
+				}
+			case "li":
+				if w.stemLen(r.suf) > 0 && strings.IndexByte("cdeghkmnrt", w.b[w.stemLen(r.suf)-1]) >= 0 {
+					w.replace(r.suf, r.rep)
+				}
+			default:
+				w.replace(r.suf, r.rep)
+			}
+		}
+		break
+	}
+
+	// Step 3
+	step3 := []struct{ suf, rep string }{
+		{"ational", "ate"}, {"tional", "tion"}, {"alize", "al"}, {"icate", "ic"},
+		{"iciti", "ic"}, {"ative", ""}, {"ical", "ic"}, {"ness", ""}, {"ful", ""},
+	}
+	for _, r := range step3 {
+		if !w.has(r.suf) {
+			continue
+		}
+		if w.stemLen(r.suf) >= w.r1 && (r.suf != "ative" || w.stemLen(r.suf) >= w.r2) {
+			w.replace(r.suf, r.rep)
+		}
+		break
+	}
+
+	// Step 4
+	step4 := []string{
+		"ement", "ance", "ence", "able", "ible", "ment", "ant", "ent", "ism", "ate",
+		"iti", "ous", "ive", "ize", "ion", "al", "er", "ic",
+	}
+	for _, suf := range step4 {
+		if !w.has(suf) {
+			continue
+		}
+		if w.stemLen(suf) >= w.r2 {
+			if suf != "ion" {
+				w.replace(suf, "")
+			} else if p := w.stemLen(suf); p > 0 && (w.b[p-1] == 's' || w.b[p-1] == 't') {
+				w.replace(suf, "")
+			}
+		}
+		break
+	}
+
+	// Step 5
+	if n := len(w.b); n > 0 {
+		switch w.b[n-1] {
+		case 'e':
+			if n-1 >= w.r2 || (n-1 >= w.r1 && !w.shortSyllableAt(n-1)) {
+				w.b = w.b[:n-1]
+			}
+		case 'l':
+			if n-1 >= w.r2 && n >= 2 && w.b[n-2] == 'l' {
+				w.b = w.b[:n-1]
+			}
+		}
+	}
+
+	return strings.ReplaceAll(string(w.b), "Y", "y")
+}
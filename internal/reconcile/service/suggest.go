@@ -0,0 +1,92 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"recon-service/internal/reconcile/model"
+)
+
+// --------- ПОДСКАЗКИ ДЛЯ OnlyA / OnlyB (opt.Suggest) ---------
+
+// defaultSuggestMin — нижняя граница схожести подсказки, если opt.SuggestMin не задан
+const defaultSuggestMin = 0.5
+
+// details — необязательные приложения к строкам результата (nil — выключено)
+type details struct {
+	explain  [][]model.Candidate  // по строкам A: рассмотренные кандидаты B
+	suggestA [][]model.Suggestion // по строкам A: похожие несопоставленные строки B
+	suggestB [][]model.Suggestion // по строкам B: похожие несопоставленные строки A
+}
+
+// buildDetails считает explain и подсказки по итоговому назначению
+func buildDetails(a, b []model.Row, idxB *Index, sim Similarity, opt model.Options, matches []match, usedB []bool) details {
+	var d details
+	if opt.Explain {
+		d.explain = explainAll(a, b, idxB, sim, opt, matches, usedB)
+	}
+	if opt.Suggest > 0 {
+		usedA := make([]bool, len(a))
+		for i, m := range matches {
+			usedA[i] = m.b >= 0
+		}
+		// индекс по A строится так же, как по B (ID строк A и так равны позициям)
+		idxA := buildIndexB(a)
+		d.suggestA = suggestAll(a, b, idxB, sim, opt, usedA, usedB)
+		d.suggestB = suggestAll(b, a, idxA, sim, opt, usedB, usedA)
+	}
+	return d
+}
+
+// suggestAll — для каждой несопоставленной строки from до opt.Suggest
+// несопоставленных строк to (по индексу to), похожих не ниже SuggestMin.
+// Это строки, не дотянувшие до порога или отсечённые guard'ом:
+// ревьюеру остаётся подтвердить пару, а не искать её по второму файлу.
+func suggestAll(from, to []model.Row, idxTo *Index, sim Similarity, opt model.Options, usedFrom, usedTo []bool) [][]model.Suggestion {
+	floor := opt.SuggestMin
+	if floor <= 0 {
+		floor = defaultSuggestMin
+	}
+	out := make([][]model.Suggestion, len(from))
+	for i, r := range from {
+		if usedFrom[i] || strings.TrimSpace(r.NameNorm) == "" {
+			continue
+		}
+		var sugg []model.Suggestion
+		for _, name := range idxTo.candidateNames(r.NameNorm, opt.TopK, trigramCutoff(opt, floor)) {
+			var free []int
+			for _, j := range idxTo.byName[name] {
+				if !usedTo[j] {
+					free = append(free, j)
+				}
+			}
+			if len(free) == 0 {
+				continue
+			}
+			s := scoreMin(sim, r.NameNorm, name, floor)
+			if s < floor {
+				continue
+			}
+			for _, j := range free {
+				sugg = append(sugg, model.Suggestion{
+					ID:    to[j].ID,
+					Name:  to[j].Name,
+					Sku:   to[j].Sku,
+					Qty:   to[j].Qty,
+					Score: s,
+				})
+			}
+		}
+		sort.SliceStable(sugg, func(x, y int) bool {
+			if sugg[x].Score != sugg[y].Score {
+				return sugg[x].Score > sugg[y].Score
+			}
+			return sugg[x].ID < sugg[y].ID
+		})
+		if len(sugg) > opt.Suggest {
+			sugg = sugg[:opt.Suggest]
+		}
+		out[i] = sugg
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"math"
+	"sort"
+
+	"recon-service/internal/reconcile/model"
+)
+
+// --------- ИТОГИ (Result.Summary) ---------
+
+// bucketStep — ширина интервала гистограммы fuzzy-оценок
+const bucketStep = 0.05
+
+// summarize считает итоги по готовым парам и остаткам. InputA/InputB
+// (до агрегации) известны только Run — он и проставляет их после.
+func summarize(a, b []model.Row, opt model.Options, matches []match, usedB []bool, rows []model.ResultRow, counts map[string]int) model.Summary {
+	s := model.Summary{
+		RowsA:    len(a),
+		RowsB:    len(b),
+		Matched:  len(rows),
+		ByMethod: make(map[string]int, len(passOrder)),
+		ByStatus: statusStats(rows),
+	}
+	for _, p := range passOrder {
+		s.ByMethod[p] = counts[p]
+	}
+	for i, m := range matches {
+		if m.b < 0 {
+			s.OnlyA++
+			s.OnlyAQty = s.OnlyAQty.Add(a[i].Qty)
+		}
+	}
+	for j, u := range usedB {
+		if !u {
+			s.OnlyB++
+			s.OnlyBQty = s.OnlyBQty.Add(b[j].Qty)
+		}
+	}
+
+	var fuzzy []float64
+	for _, r := range rows {
+		s.AbsDelta = s.AbsDelta.Add(r.Delta.Abs())
+		s.NetDelta = s.NetDelta.Add(r.Delta)
+		if r.Method == passFuzzy && r.Score != nil {
+			fuzzy = append(fuzzy, *r.Score)
+		}
+	}
+	s.Fuzzy = scoreStats(fuzzy, opt.Threshold)
+	return s
+}
+
+// scoreStats — min/max/mean/median и гистограмма от порога (вниз до
+// кратного bucketStep) до 1. Границы считаем в сотых, чтобы не получить
+// 0.8500000000000001 в JSON.
+func scoreStats(scores []float64, threshold float64) model.ScoreStats {
+	first := int(math.Floor(math.Max(threshold, 0) / bucketStep))
+	last := int(math.Round(1 / bucketStep))
+	if first >= last {
+		first = last - 1
+	}
+	st := model.ScoreStats{Buckets: make([]model.ScoreBucket, 0, last-first)}
+	for k := first; k < last; k++ {
+		st.Buckets = append(st.Buckets, model.ScoreBucket{
+			From: bucketEdge(k),
+			To:   bucketEdge(k + 1),
+		})
+	}
+	if len(scores) == 0 {
+		return st
+	}
+
+	sorted := append([]float64(nil), scores...)
+	sort.Float64s(sorted)
+	st.Count = len(sorted)
+	st.Min = sorted[0]
+	st.Max = sorted[len(sorted)-1]
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+		k := int(math.Floor(v/bucketStep)) - first
+		switch {
+		case k < 0:
+			k = 0
+		case k >= len(st.Buckets):
+			k = len(st.Buckets) - 1
+		}
+		st.Buckets[k].Count++
+	}
+	st.Mean = sum / float64(len(sorted))
+	if n := len(sorted); n%2 == 1 {
+		st.Median = sorted[n/2]
+	} else {
+		st.Median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return st
+}
+
+func bucketEdge(k int) float64 {
+	return math.Round(float64(k)*bucketStep*100) / 100
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"recon-service/internal/reconcile/dictionary"
+)
+
+// --------- «ЧИСЛО + ЕДИНИЦА»: размерности и базовые единицы ---------
+
+// Размерности количественных токенов
+const (
+	dimVolume = "volume"
+	dimMass   = "mass"
+	dimLength = "length"
+	dimCount  = "count"
+	dimSize   = "size" // габарит "1200x800"
+)
+
+// unitScale — размерность и множитель к базовой единице размерности
+type unitScale struct {
+	dim    string
+	factor float64
+}
+
+// unitScales — канонические единицы (как в словаре: мл → ml) и их перевод
+// в базовые: объём — ml, масса — g, длина — mm, штуки — pcs.
+// Единицы, которых здесь нет (pack, set, pair…), сравниваются буквально.
+var unitScales = map[string]unitScale{
+	"ml":  {dimVolume, 1},
+	"l":   {dimVolume, 1000},
+	"mg":  {dimMass, 0.001},
+	"g":   {dimMass, 1},
+	"kg":  {dimMass, 1000},
+	"mm":  {dimLength, 1},
+	"cm":  {dimLength, 10},
+	"m":   {dimLength, 1000},
+	"pcs": {dimCount, 1},
+}
+
+// baseUnits — имя базовой единицы размерности (для ключа)
+var baseUnits = map[string]string{
+	dimVolume: "ml",
+	dimMass:   "g",
+	dimLength: "mm",
+	dimCount:  "pcs",
+}
+
+// unitTolerance — относительный допуск при сравнении количеств (шум float,
+// "0,33 л" против "330 мл")
+const unitTolerance = 1e-3
+
+// numUnit — количественный токен наименования, приведённый к базовой единице
+type numUnit struct {
+	dim   string    // размерность (или сама единица, если перевод неизвестен)
+	value float64   // значение в базовой единице
+	raw   string    // для габаритов — сам токен "1200x800"
+	faces []float64 // для габаритов — грани в мм
+}
+
+// key — токен для NameKey: значение в базовой единице ("1,5 л" → "1500ml")
+func (q numUnit) key() string {
+	if q.dim == dimSize {
+		return q.raw
+	}
+	unit := baseUnits[q.dim]
+	if unit == "" {
+		unit = q.dim
+	}
+	v := math.Round(q.value*1e6) / 1e6
+	return strconv.FormatFloat(v, 'f', -1, 64) + unit
+}
+
+// parseNumUnit разбирает токен вида "<число><единица>" ("1,5л", "1500ml").
+// Единица ищется в словаре, затем среди канонических имён.
+func parseNumUnit(t string, dict *dictionary.Dictionary) (numUnit, bool) {
+	i := strings.IndexFunc(t, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.' && r != ','
+	})
+	if i <= 0 || i == len(t) {
+		return numUnit{}, false
+	}
+	v, err := strconv.ParseFloat(strings.ReplaceAll(t[:i], ",", "."), 64)
+	if err != nil {
+		return numUnit{}, false
+	}
+	alias := strings.ToLower(t[i:])
+	unit, ok := dict.Unit(alias)
+	if !ok {
+		if _, known := unitScales[alias]; !known {
+			return numUnit{}, false
+		}
+		unit = alias
+	}
+	if sc, ok := unitScales[unit]; ok {
+		return numUnit{dim: sc.dim, value: v * sc.factor}, true
+	}
+	return numUnit{dim: unit, value: v}, true
+}
+
+// extractNumUnits вытаскивает из нормализованной строки габариты (в той
+// же модели, что и NameKey, см. dims.go) и количественные токены
+// "<число><единица>"; алиасы единиц — из словаря.
+func extractNumUnits(norm string, dict *dictionary.Dictionary) []numUnit {
+	if norm == "" {
+		return nil
+	}
+	toks := strings.Fields(norm)
+	out := make([]numUnit, 0, 4)
+	for _, t := range toks {
+		if faces, ok := parseDimToken(t); ok {
+			out = append(out, numUnit{dim: dimSize, raw: t, faces: faces})
+			continue
+		}
+		if q, ok := parseNumUnit(t, dict); ok {
+			out = append(out, q)
+		}
+	}
+	return out
+}
+
+func sameNumUnit(a, b numUnit) bool {
+	if a.dim != b.dim {
+		return false
+	}
+	if a.dim == dimSize {
+		return sameDims(a.faces, b.faces)
+	}
+	d := math.Abs(a.value - b.value)
+	m := math.Max(math.Abs(a.value), math.Abs(b.value))
+	return d <= unitTolerance*m
+}
+
+// equalNumUnitsSoft — мультимножества количеств совпадают с точностью до
+// одного непарного токена: "1,5 л" == "1500 мл", "1 м" == "100 см".
+func equalNumUnitsSoft(a, b []numUnit) bool {
+	usedB := make([]bool, len(b))
+	miss := 0
+	for _, qa := range a {
+		found := false
+		for j, qb := range b {
+			if !usedB[j] && sameNumUnit(qa, qb) {
+				usedB[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			miss++
+			if miss > 1 {
+				return false
+			}
+		}
+	}
+	for _, u := range usedB {
+		if !u {
+			miss++
+		}
+	}
+	return miss <= 1
+}
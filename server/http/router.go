@@ -6,11 +6,12 @@ import (
 
 	"recon-service/internal/config"
 	"recon-service/internal/middleware"
+	"recon-service/internal/reconcile/dictionary"
 	"recon-service/server/http/handlers"
 	recHnd "recon-service/internal/reconcile/handler"
 )
 
-func NewRouter(cfg config.Config, logger zerolog.Logger) *chi.Mux {
+func NewRouter(cfg config.Config, logger zerolog.Logger, dicts *dictionary.Registry) *chi.Mux {
 	r := chi.NewRouter()
 
 	// порядок важен: recover -> requestID -> logging -> cors -> limit
@@ -24,7 +25,26 @@ func NewRouter(cfg config.Config, logger zerolog.Logger) *chi.Mux {
 	r.Get("/health", handlers.Health)
 
 	// основной эндпоинт
-	r.Post("/reconcile", recHnd.Reconcile(cfg, logger))
+	r.Post("/reconcile", recHnd.Reconcile(cfg, logger, dicts))
+
+	// управление словарями нормализации (только с ADMIN_TOKEN)
+	if cfg.AdminToken != "" {
+		adm := recHnd.DictionaryAdmin{Reg: dicts, Logger: logger}
+		r.Route("/admin/dictionaries", func(r chi.Router) {
+			r.Use(middleware.AdminToken(cfg.AdminToken))
+			r.Get("/", adm.List)
+			r.Post("/", adm.Create)
+			r.Post("/reload", adm.Reload)
+			r.Get("/{name}", adm.Get)
+			r.Put("/{name}", adm.Put)
+			r.Delete("/{name}", adm.Delete)
+			r.Get("/{name}/versions", adm.Versions)
+			r.Get("/{name}/versions/{version}", adm.Version)
+			r.Post("/{name}/versions/{version}/activate", adm.Rollback)
+		})
+	} else {
+		logger.Info().Msg("ADMIN_TOKEN not set: /admin/dictionaries disabled")
+	}
 
 	return r
 }